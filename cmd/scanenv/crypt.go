@@ -0,0 +1,245 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/taybart/env"
+)
+
+// commands : subcommands for encrypted env files, picked by the first argument
+var commands = map[string]func(argv []string) error{
+	"keygen":  keygen,
+	"encrypt": encrypt,
+	"decrypt": decrypt,
+	"rotate":  rotate,
+	"edit":    edit,
+}
+
+// keyFlags : flags selecting the encryption key
+func keyFlags(fs *flag.FlagSet) *env.KeySource {
+	ks := &env.KeySource{}
+	fs.StringVar(&ks.File, "key-file", "", "file holding the base64 key")
+	fs.StringVar(&ks.Var, "key-var", "", "variable holding the base64 key (default ENV_KEY)")
+	return ks
+}
+
+// fileArg : parse argv and return the single file argument
+func fileArg(fs *flag.FlagSet, argv []string) (string, error) {
+	if err := fs.Parse(argv); err != nil {
+		return "", err
+	}
+	if fs.NArg() != 1 {
+		return "", fmt.Errorf("usage: scanenv %s [flags] FILE", fs.Name())
+	}
+	return fs.Arg(0), nil
+}
+
+// writeFile : replace path keeping its permissions, new files are 0600
+func writeFile(path string, data []byte) error {
+	perm := os.FileMode(0o600)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".scanenv-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func keygen(argv []string) error {
+	fs := flag.NewFlagSet("keygen", flag.ExitOnError)
+	out := fs.String("o", "", "write the key to this file instead of stdout")
+	fs.Parse(argv)
+	key, err := env.GenerateKey()
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		fmt.Println(key)
+		return nil
+	}
+	if _, err := os.Stat(*out); err == nil {
+		return fmt.Errorf("%s already exists, use rotate to replace a key", *out)
+	}
+	return os.WriteFile(*out, []byte(key+"\n"), 0o600)
+}
+
+func encrypt(argv []string) error {
+	fs := flag.NewFlagSet("encrypt", flag.ExitOnError)
+	ks := keyFlags(fs)
+	whole := fs.Bool("whole", false, "encrypt the whole file instead of each value")
+	out := fs.String("o", "", "write to this file instead of replacing FILE")
+	path, err := fileArg(fs, argv)
+	if err != nil {
+		return err
+	}
+	key, err := env.ReadKey(*ks)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	mode := env.EncryptValues
+	if *whole {
+		mode = env.EncryptFile
+	}
+	enc, err := env.EncryptDotenv(data, key, mode)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if *out == "" {
+		*out = path
+	}
+	return writeFile(*out, enc)
+}
+
+func decrypt(argv []string) error {
+	fs := flag.NewFlagSet("decrypt", flag.ExitOnError)
+	ks := keyFlags(fs)
+	out := fs.String("o", "", "write to this file instead of stdout")
+	path, err := fileArg(fs, argv)
+	if err != nil {
+		return err
+	}
+	plain, _, err := readEncrypted(path, *ks)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(plain)
+		return err
+	}
+	return writeFile(*out, plain)
+}
+
+func rotate(argv []string) error {
+	fs := flag.NewFlagSet("rotate", flag.ExitOnError)
+	ks := keyFlags(fs)
+	newKeyFile := fs.String("new-key-file", "", "file holding the new key, generated when missing")
+	path, err := fileArg(fs, argv)
+	if err != nil {
+		return err
+	}
+	if *newKeyFile == "" {
+		return errors.New("rotate needs -new-key-file")
+	}
+	plain, mode, err := readEncrypted(path, *ks)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(*newKeyFile); errors.Is(err, os.ErrNotExist) {
+		key, err := env.GenerateKey()
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*newKeyFile, []byte(key+"\n"), 0o600); err != nil {
+			return err
+		}
+	}
+	key, err := env.ReadKey(env.KeySource{File: *newKeyFile})
+	if err != nil {
+		return err
+	}
+	enc, err := env.EncryptDotenv(plain, key, mode)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, enc)
+}
+
+func edit(argv []string) error {
+	fs := flag.NewFlagSet("edit", flag.ExitOnError)
+	ks := keyFlags(fs)
+	whole := fs.Bool("whole", false, "encrypt the whole file when creating it")
+	path, err := fileArg(fs, argv)
+	if err != nil {
+		return err
+	}
+	key, err := env.ReadKey(*ks)
+	if err != nil {
+		return err
+	}
+	plain, mode := []byte{}, env.EncryptValues
+	if *whole {
+		mode = env.EncryptFile
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		plain, mode, err = env.DecryptDotenv(data, key)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	tmp, err := os.CreateTemp("", "scanenv-*.env")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(plain)
+	tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+	// EDITOR may have arguments, ex. "code --wait"
+	parts := append(strings.Fields(editor), tmp.Name())
+	cmd := exec.Command(parts[0], parts[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", editor, err)
+	}
+
+	edited, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return err
+	}
+	enc, err := env.EncryptDotenv(edited, key, mode)
+	if err != nil {
+		return fmt.Errorf("not saved, %w", err)
+	}
+	return writeFile(path, enc)
+}
+
+// readEncrypted : decrypt path with the key from ks
+func readEncrypted(path string, ks env.KeySource) ([]byte, env.EncryptMode, error) {
+	key, err := env.ReadKey(ks)
+	if err != nil {
+		return nil, env.EncryptValues, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, env.EncryptValues, err
+	}
+	plain, mode, err := env.DecryptDotenv(data, key)
+	if err != nil {
+		return nil, mode, fmt.Errorf("%s: %w", path, err)
+	}
+	return plain, mode, nil
+}
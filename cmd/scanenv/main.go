@@ -14,7 +14,7 @@ var (
 		Name:    "scanenv",
 		Version: "v0.0.1",
 		Author:  "Taylor Bartlett <taybart@email.com>",
-		About:   "check for defined env vars in a project or file",
+		About:   "check for defined env vars in a project or file\n\tscanenv keygen|encrypt|decrypt|rotate|edit -h for encrypted env files",
 		Args: map[string]*args.Arg{
 			"files": {
 				Short: "f",
@@ -42,6 +42,17 @@ var (
 )
 
 func main() {
+	// args has no subcommands, encrypted file commands are picked by hand
+	if len(os.Args) > 1 {
+		if run, ok := commands[os.Args[1]]; ok {
+			if err := run(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	if err := app.Parse(); err != nil {
 		if errors.Is(err, args.ErrUsageRequested) {
 			return
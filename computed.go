@@ -0,0 +1,81 @@
+package env
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+/* AddComputed : declare key with a default computed by fn, for defaults that
+ * depend on the machine the program runs on
+ *   env.AddComputed("WORKERS | int", func() (string, error) {
+ *     return strconv.Itoa(runtime.NumCPU()), nil
+ *   })
+ *   env.AddComputed("HOST", os.Hostname)
+ * fn is only called when key is unset and at most once, its provenance is
+ * FromComputed with the name of fn as the location.
+ */
+func (e *Environment) AddComputed(key string, fn func() (string, error)) {
+	err := e.EnsureComputed(key, fn)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// EnsureComputed : same as AddComputed but returns the error
+func (e *Environment) EnsureComputed(key string, fn func() (string, error)) error {
+	return e.WithPrefix("").EnsureComputed(key, fn)
+}
+
+// AddComputed : same as env.AddComputed with key prefixed
+func (s Scope) AddComputed(key string, fn func() (string, error)) {
+	err := s.EnsureComputed(key, fn)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// EnsureComputed : same as env.EnsureComputed with key prefixed
+func (s Scope) EnsureComputed(key string, fn func() (string, error)) error {
+	e := s.environment()
+	if e.IsFrozen() {
+		return ErrFrozen
+	}
+	spec, err := ParseSpec(key)
+	if err != nil {
+		return err
+	}
+	if spec.HasDefault {
+		return &SpecError{Spec: key, Offset: len(spec.Name), Reason: "computed values cannot have a default"}
+	}
+	spec.Name = s.Key(spec.Name)
+
+	name := funcName(fn)
+	r := e.reg()
+	if def, ok := r.defaults[spec.Name]; ok && r.computed[spec.Name] != "" {
+		// already computed by an earlier declaration
+		spec.Default, spec.HasDefault = def, true
+	} else if _, found := e.resolve(spec.Name); !found {
+		val, err := fn()
+		if err != nil {
+			return fmt.Errorf("computing default for %s with %s: %w", spec.Name, name, err)
+		}
+		spec.Default, spec.HasDefault = val, true
+	}
+	e.update(func(r *registry) { r.computed[spec.Name] = name })
+	return e.ensure([]Spec{spec})
+}
+
+// funcName : the package qualified name of fn, ex. os.Hostname
+func funcName(fn any) string {
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return "func"
+	}
+	name := f.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
@@ -0,0 +1,53 @@
+package env_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/taybart/env"
+	"github.com/taybart/env/envtest"
+)
+
+func workers() (string, error) { return "4", nil }
+
+func TestComputed(t *testing.T) {
+	is := is.New(t)
+	e := envtest.New(t, map[string]string{"SET": "from env"})
+	e.DefaultsInProcess(false)
+
+	calls := 0
+	counted := func() (string, error) {
+		calls++
+		return "computed", nil
+	}
+	e.AddComputed("WORKERS | int", workers)
+	is.Equal(e.Int("WORKERS"), 4)
+	is.True(e.IsDefault("WORKERS"))
+	is.Equal(e.ProvenanceOf("WORKERS"), env.Provenance{Kind: env.FromComputed, Location: "env_test.workers"})
+
+	// fn is not called when the key is set
+	e.AddComputed("SET", counted)
+	is.Equal(calls, 0)
+	is.Equal(e.Get("SET"), "from env")
+	is.Equal(e.ProvenanceOf("SET").Kind, env.FromEnv)
+
+	// and only once when declared again
+	e.AddComputed("LAZY", counted)
+	e.AddComputed("LAZY", counted)
+	is.Equal(calls, 1)
+	is.Equal(e.Get("LAZY"), "computed")
+
+	e.WithPrefix("DB_").AddComputed("HOST", os.Hostname)
+	is.Equal(e.ProvenanceOf("DB_HOST").Location, "os.Hostname")
+
+	boom := errors.New("boom")
+	err := e.EnsureComputed("BROKEN", func() (string, error) { return "", boom })
+	is.True(errors.Is(err, boom))
+	err = e.EnsureComputed("BAD=default", workers)
+	is.True(errors.Is(err, env.ErrInvalidSpec))
+	// validators run against the computed value
+	err = e.EnsureComputed("NOT_INT | int", func() (string, error) { return "x", nil })
+	is.True(err != nil)
+}
@@ -0,0 +1,105 @@
+package env
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Encoding : how Decode turns a value into bytes
+type Encoding int
+
+const (
+	// Base64 is standard padded base64, the default
+	Base64 Encoding = iota
+	// Base64URL is URL safe padded base64
+	Base64URL
+	// Base64Raw is standard base64 without padding
+	Base64Raw
+	// Base64RawURL is URL safe base64 without padding, as used by JWTs
+	Base64RawURL
+	// Hex is hexadecimal, either case
+	Hex
+	// AutoEncoding picks hex when the value is only hex digits, otherwise the
+	// base64 variant matching its alphabet and padding
+	AutoEncoding
+)
+
+func (enc Encoding) String() string {
+	switch enc {
+	case Base64:
+		return "base64"
+	case Base64URL:
+		return "base64url"
+	case Base64Raw:
+		return "base64raw"
+	case Base64RawURL:
+		return "base64rawurl"
+	case Hex:
+		return "hex"
+	case AutoEncoding:
+		return "auto"
+	}
+	return fmt.Sprintf("Encoding(%d)", int(enc))
+}
+
+// decode : decode val, errors never include the value
+func (enc Encoding) decode(val string) ([]byte, error) {
+	var decoded []byte
+	var err error
+	switch enc {
+	case Base64:
+		decoded, err = base64.StdEncoding.DecodeString(val)
+	case Base64URL:
+		decoded, err = base64.URLEncoding.DecodeString(val)
+	case Base64Raw:
+		decoded, err = base64.RawStdEncoding.DecodeString(val)
+	case Base64RawURL:
+		decoded, err = base64.RawURLEncoding.DecodeString(val)
+	case Hex:
+		decoded, err = hex.DecodeString(val)
+	case AutoEncoding:
+		return detectEncoding(val).decode(val)
+	default:
+		return nil, fmt.Errorf("unknown encoding %s", enc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", enc, err)
+	}
+	return decoded, nil
+}
+
+// detectEncoding : guess the encoding of val. Hex digits are also valid
+// base64, they are taken as hex since that is far more likely.
+func detectEncoding(val string) Encoding {
+	if len(val)%2 == 0 && strings.Trim(val, "0123456789abcdefABCDEF") == "" {
+		return Hex
+	}
+	url := strings.ContainsAny(val, "-_")
+	padded := strings.HasSuffix(val, "=") || (len(val)%4 == 0 && !url)
+	switch {
+	case url && padded:
+		return Base64URL
+	case url:
+		return Base64RawURL
+	case padded:
+		return Base64
+	}
+	return Base64Raw
+}
+
+// DecodeTo : decode the environment value like Decode, then unmarshal the
+// JSON into out. Unset optional keys leave out untouched.
+func (e *Environment) DecodeTo(key string, out any, enc ...Encoding) error {
+	decoded, err := e.Decode(key, enc...)
+	if err != nil {
+		return fmt.Errorf("could not decode %s: %w", key, err)
+	}
+	if decoded == nil {
+		if _, found := e.lookup(key); !found {
+			return nil
+		}
+	}
+	return unmarshal(e.reg().specs[key], key, decoded, out, JSONOptions{})
+}
@@ -0,0 +1,83 @@
+package env_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/taybart/env"
+	"github.com/taybart/env/envtest"
+)
+
+func TestDecodeEncodings(t *testing.T) {
+	is := is.New(t)
+	// "\xfb\xff" encodes with + and / in std base64 and - and _ in url base64
+	want := "\xfb\xffcool"
+	e := envtest.New(t, map[string]string{
+		"STD":     "+/9jb29s",
+		"URL":     "-_9jb29s",
+		"RAW":     "+/9jb29sIQ",
+		"RAW_URL": "-_9jb29sIQ",
+		"HEX":     "FBFF636F6F6C",
+		"PADDED":  "+/9jb29sIQ==",
+	})
+	e.Add([]string{"STD", "URL", "RAW", "RAW_URL", "HEX", "PADDED", "MISSING?"})
+
+	for key, enc := range map[string]env.Encoding{
+		"STD":     env.Base64,
+		"URL":     env.Base64URL,
+		"RAW":     env.Base64Raw,
+		"RAW_URL": env.Base64RawURL,
+		"HEX":     env.Hex,
+		"PADDED":  env.Base64,
+	} {
+		suffix := ""
+		if strings.HasPrefix(key, "RAW") || key == "PADDED" {
+			suffix = "!"
+		}
+		val, err := e.Decode(key, enc)
+		is.NoErr(err)
+		is.Equal(string(val), want+suffix)
+
+		val, err = e.Decode(key, env.AutoEncoding)
+		is.NoErr(err)
+		is.Equal(string(val), want+suffix)
+	}
+
+	// std stays the default
+	val, err := e.Decode("STD")
+	is.NoErr(err)
+	is.Equal(string(val), want)
+	_, err = e.Decode("URL")
+	is.True(err != nil)
+
+	val, err = e.Decode("MISSING", env.Hex)
+	is.NoErr(err)
+	is.True(val == nil)
+}
+
+func TestDecodeTo(t *testing.T) {
+	is := is.New(t)
+	e := envtest.New(t, map[string]string{
+		// {"host":"db","port":5432}
+		"DB":     "eyJob3N0IjoiZGIiLCJwb3J0Ijo1NDMyfQ",
+		"BROKEN": "e30K" + "e30K", // "{}\n{}\n"
+	})
+	e.Add([]string{"DB", "BROKEN", "MISSING?"})
+
+	var db struct {
+		Host string
+		Port int
+	}
+	is.NoErr(e.DecodeTo("DB", &db, env.AutoEncoding))
+	is.Equal(db.Host, "db")
+	is.Equal(db.Port, 5432)
+
+	db.Host = "untouched"
+	is.NoErr(e.DecodeTo("MISSING", &db))
+	is.Equal(db.Host, "untouched")
+
+	err := e.DecodeTo("BROKEN", &db)
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), "offset"))
+}
@@ -0,0 +1,233 @@
+package env
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// fileValue : a value loaded from a file and where it came from
+type fileValue struct {
+	value string
+	path  string
+	// jsonPath of the value in a JSON config file, ex. $.db.host
+	jsonPath string
+}
+
+// fileReader : reads a loaded file, again on every Reload
+type fileReader func() (map[string]fileValue, error)
+
+// atPath : values read from path
+func atPath(values map[string]string, path string) map[string]fileValue {
+	loaded := make(map[string]fileValue, len(values))
+	for k, v := range values {
+		loaded[k] = fileValue{value: v, path: path}
+	}
+	return loaded
+}
+
+// fileLayer : the current values loaded from files
+func (e *Environment) fileLayer() map[string]fileValue {
+	return *e.fileValues.Load()
+}
+
+// readFiles : parse paths in order into a new file layer, filesMu must be held
+func (e *Environment) readFiles(paths []string) (map[string]fileValue, error) {
+	layer := make(map[string]fileValue)
+	for _, path := range paths {
+		values, err := e.readers[path]()
+		if err != nil {
+			return nil, err
+		}
+		for k, fv := range values {
+			layer[k] = fv
+		}
+	}
+	return layer, nil
+}
+
+// readFile : parse a plain dotenv file
+func readFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	values, err := ParseDotenv(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return values, nil
+}
+
+/* ParseDotenv : parse a dotenv file
+ *   # comments and blank lines are skipped
+ *   export KEY=value   # export is optional, unquoted values end at " #"
+ *   KEY="with \n escapes"
+ *   KEY='taken literally'
+ */
+func ParseDotenv(r io.Reader) (map[string]string, error) {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		key, val, ok, err := parseLine(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if ok {
+			values[key] = val
+		}
+	}
+	return values, scanner.Err()
+}
+
+// parseLine : parse one line of a dotenv file, ok is false for blank lines
+// and comments
+func parseLine(text string) (key, val string, ok bool, err error) {
+	text = strings.TrimSpace(text)
+	if text == "" || text[0] == '#' {
+		return "", "", false, nil
+	}
+	text = strings.TrimPrefix(text, "export ")
+	key, val, ok = strings.Cut(text, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return "", "", false, errors.New("expected KEY=value")
+	}
+	for i := 0; i < len(key); i++ {
+		if !isNameByte(key[i], i == 0) {
+			return "", "", false, fmt.Errorf("invalid key %q", key)
+		}
+	}
+	val = strings.TrimSpace(val)
+	switch {
+	case strings.HasPrefix(val, `"`):
+		quoted, err := strconv.QuotedPrefix(val)
+		if err != nil {
+			return "", "", false, fmt.Errorf("unterminated quote for %s", key)
+		}
+		val, _ = strconv.Unquote(quoted)
+	case strings.HasPrefix(val, "'"):
+		end := strings.IndexByte(val[1:], '\'')
+		if end < 0 {
+			return "", "", false, fmt.Errorf("unterminated quote for %s", key)
+		}
+		val = val[1 : end+1]
+	default:
+		if i := strings.Index(val, " #"); i >= 0 {
+			val = strings.TrimSpace(val[:i])
+		}
+	}
+	return key, val, true, nil
+}
+
+// LoadFile : load a dotenv file as a fallback for the process env. Values
+// are kept in memory, later files override earlier ones and the process env
+// overrides all of them.
+func (e *Environment) LoadFile(path string) error {
+	return e.loadFile(path, func() (map[string]fileValue, error) {
+		values, err := readFile(path)
+		return atPath(values, path), err
+	})
+}
+
+// loadFile : layer the values from read over the current files, read is
+// called again on Reload
+func (e *Environment) loadFile(path string, read fileReader) error {
+	loaded, err := read()
+	if err != nil {
+		return err
+	}
+	e.filesMu.Lock()
+	defer e.filesMu.Unlock()
+	current := e.fileLayer()
+	layer := make(map[string]fileValue, len(current)+len(loaded))
+	for k, fv := range current {
+		layer[k] = fv
+	}
+	for k, fv := range loaded {
+		layer[k] = fv
+	}
+	e.fileValues.Store(&layer)
+	e.loadedFiles = append(e.loadedFiles, path)
+	e.readers[path] = read
+	return nil
+}
+
+// ProfileConfig : which env files LoadProfile reads
+type ProfileConfig struct {
+	// Dir containing the files, defaults to the working directory
+	Dir string
+	// Profile to load, ex. production loads .env.production
+	Profile string
+	// Var to read the profile from when Profile is empty, defaults to APP_ENV.
+	// It is looked up in the process env and then in .env
+	Var string
+}
+
+/* LoadProfile : load env files for a profile, later files override earlier ones
+ *   .env
+ *   .env.<profile>
+ *   .env.<profile>.local
+ * Missing files are skipped. Returns the files that were loaded.
+ */
+func (e *Environment) LoadProfile(cfg ProfileConfig) ([]string, error) {
+	if cfg.Var == "" {
+		cfg.Var = "APP_ENV"
+	}
+	base := filepath.Join(cfg.Dir, ".env")
+
+	loaded := []string{}
+	load := func(path string) error {
+		err := e.LoadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err == nil {
+			loaded = append(loaded, path)
+		}
+		return err
+	}
+
+	if err := load(base); err != nil {
+		return loaded, err
+	}
+	profile := cfg.Profile
+	if profile == "" {
+		if p, found := e.source.Lookup(cfg.Var); found {
+			profile = p
+		} else if fv, found := e.fileLayer()[cfg.Var]; found {
+			profile = fv.value
+		}
+	}
+	if profile == "" {
+		return loaded, nil
+	}
+	for _, path := range []string{base + "." + profile, base + "." + profile + ".local"} {
+		if err := load(path); err != nil {
+			return loaded, err
+		}
+	}
+	return loaded, nil
+}
+
+// undeclaredFileKeys : keys set by env files that were never declared
+func (e *Environment) undeclaredFileKeys() []string {
+	keys := []string{}
+	for k := range e.fileLayer() {
+		if _, ok := e.reg().specs[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,90 @@
+package env_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/taybart/env"
+	"github.com/taybart/env/envtest"
+)
+
+func TestParseDotenv(t *testing.T) {
+	is := is.New(t)
+	values, err := env.ParseDotenv(strings.NewReader(`
+# comment
+PLAIN=value # trailing comment
+export EXPORTED=yes
+DOUBLE="line\nbreak # kept"
+SINGLE='raw \n'
+EMPTY=
+COLOR=#fff
+`))
+	is.NoErr(err)
+	is.Equal(values, map[string]string{
+		"PLAIN":    "value",
+		"EXPORTED": "yes",
+		"DOUBLE":   "line\nbreak # kept",
+		"SINGLE":   `raw \n`,
+		"EMPTY":    "",
+		"COLOR":    "#fff",
+	})
+
+	_, err = env.ParseDotenv(strings.NewReader("NOT A PAIR"))
+	is.True(err != nil)
+}
+
+func TestLoadProfile(t *testing.T) {
+	is := is.New(t)
+	e := envtest.Global(t, nil)
+	dir := t.TempDir()
+	write := func(name, contents string) {
+		is.NoErr(os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o600))
+	}
+	write(".env", "TEST_PROFILE_ENV=staging\nTEST_PROFILE_A=base\nTEST_PROFILE_B=base\nTEST_PROFILE_C=base\n")
+	write(".env.staging", "TEST_PROFILE_B=staging\nTEST_PROFILE_C=staging\nTEST_PROFILE_STRAY=1\n")
+	write(".env.staging.local", "TEST_PROFILE_C=local\n")
+	e.Setenv("TEST_PROFILE_D", "process")
+
+	loaded, err := env.LoadProfile(env.ProfileConfig{Dir: dir, Var: "TEST_PROFILE_ENV"})
+	is.NoErr(err)
+	is.Equal(len(loaded), 3)
+
+	env.Add([]string{"TEST_PROFILE_A", "TEST_PROFILE_B", "TEST_PROFILE_C", "TEST_PROFILE_D"})
+	is.Equal(env.Get("TEST_PROFILE_A"), "base")
+	is.Equal(env.Get("TEST_PROFILE_B"), "staging")
+	is.Equal(env.Get("TEST_PROFILE_C"), "local")
+	is.Equal(env.Get("TEST_PROFILE_D"), "process")
+
+	p := env.ProvenanceOf("TEST_PROFILE_C")
+	is.Equal(p.Kind, env.FromFile)
+	is.Equal(p.Location, filepath.Join(dir, ".env.staging.local"))
+
+	report := env.ConfigReport()
+	warned := false
+	for _, w := range report.Warnings {
+		warned = warned || strings.HasPrefix(w, "TEST_PROFILE_STRAY")
+	}
+	is.True(warned) // undeclared keys from profile files are reported
+}
+
+func TestLoadFileAfterEnsure(t *testing.T) {
+	is := is.New(t)
+	e := envtest.New(t, nil)
+	path := filepath.Join(t.TempDir(), ".env")
+	is.NoErr(os.WriteFile(path, []byte("CHK_LEVEL=debug\n"), 0o600))
+
+	// the default is written to the source, the file loaded later still wins
+	e.Add([]string{"CHK_LEVEL=info"})
+	is.NoErr(e.LoadFile(path))
+	is.Equal(e.Get("CHK_LEVEL"), "debug")
+	is.Equal(e.ProvenanceOf("CHK_LEVEL").Kind, env.FromFile)
+	is.Equal(e.Environ(env.EnvironOptions{}), []string{"CHK_LEVEL=debug"})
+
+	// a value set in the env still wins over the file
+	e.Setenv("CHK_LEVEL", "warn")
+	is.Equal(e.Get("CHK_LEVEL"), "warn")
+	is.Equal(e.ProvenanceOf("CHK_LEVEL").Kind, env.FromEnv)
+}
@@ -0,0 +1,82 @@
+package env
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// DumpFormat : output format for Dump
+type DumpFormat int
+
+const (
+	// DumpDotenv writes KEY="value" lines that ParseDotenv can read back
+	DumpDotenv DumpFormat = iota
+	// DumpJSON writes an array of objects, one per key
+	DumpJSON
+)
+
+// dumpVar : a key as written by DumpJSON
+type dumpVar struct {
+	Name       string  `json:"name"`
+	Value      *string `json:"value"`
+	Default    bool    `json:"default,omitempty"`
+	Optional   bool    `json:"optional,omitempty"`
+	Secret     bool    `json:"secret,omitempty"`
+	Provenance string  `json:"provenance"`
+}
+
+/* Dump : write the resolved value of every declared key to w, sorted by name
+ * with secrets redacted. In dotenv format values from defaults are marked
+ * with a comment and unset optional keys are commented out
+ *   PORT="6969" # default
+ *   HOST="box" # computed by os.Hostname
+ *   TOKEN="<redacted>"
+ *   # SECURE= (optional, unset)
+ */
+func (e *Environment) Dump(w io.Writer, format DumpFormat) error {
+	vars := e.ConfigReport().Vars
+	switch format {
+	case DumpDotenv:
+		for _, v := range vars {
+			var err error
+			switch {
+			case !v.Set && v.Spec.Optional:
+				_, err = fmt.Fprintf(w, "# %s= (optional, unset)\n", v.Spec.Name)
+			case !v.Set:
+				_, err = fmt.Fprintf(w, "# %s= (unset)\n", v.Spec.Name)
+			case v.Provenance.Kind == FromDefault:
+				_, err = fmt.Fprintf(w, "%s=%s # default\n", v.Spec.Name, strconv.Quote(v.Value))
+			case v.Provenance.Kind == FromComputed:
+				_, err = fmt.Fprintf(w, "%s=%s # computed by %s\n", v.Spec.Name, strconv.Quote(v.Value), v.Provenance.Location)
+			default:
+				_, err = fmt.Fprintf(w, "%s=%s\n", v.Spec.Name, strconv.Quote(v.Value))
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case DumpJSON:
+		out := make([]dumpVar, 0, len(vars))
+		for _, v := range vars {
+			dv := dumpVar{
+				Name:       v.Spec.Name,
+				Default:    v.Provenance.Kind == FromDefault || v.Provenance.Kind == FromComputed,
+				Optional:   v.Spec.Optional,
+				Secret:     v.Spec.Secret,
+				Provenance: v.Provenance.String(),
+			}
+			if v.Set {
+				val := v.Value
+				dv.Value = &val
+			}
+			out = append(out, dv)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+	return fmt.Errorf("unknown dump format %d", format)
+}
@@ -0,0 +1,55 @@
+package env_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/taybart/env"
+	"github.com/taybart/env/envtest"
+)
+
+func TestDump(t *testing.T) {
+	is := is.New(t)
+	e := envtest.New(t, map[string]string{
+		"HOST":  "example.com",
+		"TOKEN": "hunter2",
+	})
+	e.DefaultsInProcess(false)
+	e.Add([]string{"HOST", "PORT=6969", "TOKEN!", "SECURE?"})
+
+	var buf bytes.Buffer
+	is.NoErr(e.Dump(&buf, env.DumpDotenv))
+	is.Equal(buf.String(), `HOST="example.com"
+PORT="6969" # default
+# SECURE= (optional, unset)
+TOKEN="<redacted>"
+`)
+
+	// the dotenv output reads back
+	values, err := env.ParseDotenv(&buf)
+	is.NoErr(err)
+	is.Equal(values, map[string]string{"HOST": "example.com", "PORT": "6969", "TOKEN": env.Redacted})
+
+	buf.Reset()
+	is.NoErr(e.Dump(&buf, env.DumpJSON))
+	var vars []struct {
+		Name     string
+		Value    *string
+		Default  bool
+		Optional bool
+		Secret   bool
+	}
+	is.NoErr(json.Unmarshal(buf.Bytes(), &vars))
+	is.Equal(len(vars), 4)
+	is.Equal(vars[1].Name, "PORT")
+	is.True(vars[1].Default)
+	is.Equal(vars[2].Name, "SECURE")
+	is.True(vars[2].Optional)
+	is.True(vars[2].Value == nil)
+	is.True(vars[3].Secret)
+	is.Equal(*vars[3].Value, env.Redacted)
+
+	is.True(e.Dump(&buf, env.DumpFormat(42)) != nil)
+}
@@ -0,0 +1,346 @@
+package env
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/* Encrypted dotenv files use AES-256-GCM with a 32 byte key, stored as base64
+ * in a key file or variable. Every encrypted blob looks like
+ *   enc:v1:<key id>:<base64 nonce and ciphertext>
+ * where the key id is the start of the key's SHA-256, so a wrong key is told
+ * apart from a modified file. Values are bound to their key name, moving an
+ * encrypted value to another key is detected as tampering. Files encrypted
+ * per value end with a sealed manifest of every key and its blob
+ *   # manifest enc:v1:<key id>:<base64>
+ * so adding, dropping or replaying lines is detected too, and plain values
+ * are rejected.
+ */
+
+// EncryptMode : how EncryptDotenv encrypts a file
+type EncryptMode int
+
+const (
+	// EncryptValues encrypts every value, keys and comments stay readable
+	EncryptValues EncryptMode = iota
+	// EncryptFile encrypts the whole file
+	EncryptFile
+)
+
+var (
+	// ErrWrongKey is returned when data was encrypted with a different key
+	ErrWrongKey = errors.New("wrong encryption key")
+	// ErrTampered is returned when encrypted data fails authentication
+	ErrTampered = errors.New("encrypted data was modified")
+)
+
+const encPrefix = "enc:v1:"
+
+// manifestPrefix : starts the manifest line of a file encrypted per value,
+// a comment so plain dotenv parsers skip it
+const manifestPrefix = "# manifest "
+
+// fileHeader : first line of a file encrypted with EncryptFile
+const fileHeader = "# encrypted with github.com/taybart/env, edit with scanenv edit\n"
+
+// KeySource : where to read an encryption key, Var is tried before File
+type KeySource struct {
+	// Var holding the key, ENV_KEY when both fields are empty
+	Var string
+	// File holding the key
+	File string
+}
+
+// GenerateKey : a new random key, base64 encoded
+func GenerateKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ReadKey : read the key from ks, the variable is looked up in the source
+func (e *Environment) ReadKey(ks KeySource) (string, error) {
+	if ks.Var == "" && ks.File == "" {
+		ks.Var = "ENV_KEY"
+	}
+	if ks.Var != "" {
+		if key, found := e.source.Lookup(ks.Var); found {
+			_, err := parseKey(key)
+			return key, err
+		}
+		if ks.File == "" {
+			return "", fmt.Errorf("encryption key variable %s is not set", ks.Var)
+		}
+	}
+	data, err := os.ReadFile(ks.File)
+	if err != nil {
+		return "", fmt.Errorf("reading encryption key: %w", err)
+	}
+	key := strings.TrimSpace(string(data))
+	if _, err := parseKey(key); err != nil {
+		return "", fmt.Errorf("%s: %w", ks.File, err)
+	}
+	return key, nil
+}
+
+// parseKey : decode a base64 key
+func parseKey(key string) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil {
+		return nil, fmt.Errorf("encryption key is not base64: %w", err)
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(raw))
+	}
+	return raw, nil
+}
+
+// keyID : short fingerprint of key, safe to store next to the ciphertext
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// seal : encrypt plain, aad is the key name for values and empty for files
+func seal(key, plain []byte, aad string) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, plain, []byte(aad))
+	return encPrefix + keyID(key) + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// unseal : decrypt a blob made by seal
+func unseal(key []byte, blob, aad string) ([]byte, error) {
+	id, data, ok := strings.Cut(strings.TrimPrefix(blob, encPrefix), ":")
+	if !ok {
+		return nil, fmt.Errorf("%w: malformed encrypted value", ErrTampered)
+	}
+	if id != keyID(key) {
+		return nil, fmt.Errorf("%w: encrypted with key %s, this key is %s", ErrWrongKey, id, keyID(key))
+	}
+	sealed, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTampered, err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("%w: encrypted value is too short", ErrTampered)
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(aad))
+	if err != nil {
+		return nil, ErrTampered
+	}
+	return plain, nil
+}
+
+// encryptedBlob : the blob of a file encrypted with EncryptFile
+func encryptedBlob(data []byte) (string, bool) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		return line, strings.HasPrefix(line, encPrefix)
+	}
+	return "", false
+}
+
+// manifest : what the manifest of a file encrypted per value seals, every
+// key with a hash of its encrypted value
+func manifest(blobs map[string]string) []byte {
+	keys := make([]string, 0, len(blobs))
+	for k := range blobs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for _, k := range keys {
+		sum := sha256.Sum256([]byte(blobs[k]))
+		fmt.Fprintf(&sb, "%s %x\n", k, sum)
+	}
+	return []byte(sb.String())
+}
+
+// EncryptDotenv : encrypt a dotenv file with a base64 key. With EncryptValues
+// values that are already encrypted are kept, so plain lines can be added to
+// an encrypted file and encrypted again, the manifest is rewritten.
+func EncryptDotenv(data []byte, key string, mode EncryptMode) ([]byte, error) {
+	raw, err := parseKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if _, whole := encryptedBlob(data); whole {
+		return nil, errors.New("file is already encrypted")
+	}
+	if mode == EncryptFile {
+		if _, err := ParseDotenv(bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+		blob, err := seal(raw, data, "")
+		if err != nil {
+			return nil, err
+		}
+		return []byte(fileHeader + blob + "\n"), nil
+	}
+
+	var out bytes.Buffer
+	blobs := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.HasPrefix(scanner.Text(), manifestPrefix) {
+			continue // replaced below
+		}
+		k, v, ok, err := parseLine(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if !ok || strings.HasPrefix(v, encPrefix) {
+			if ok {
+				blobs[k] = v
+			}
+			out.WriteString(scanner.Text() + "\n")
+			continue
+		}
+		blob, err := seal(raw, []byte(v), k)
+		if err != nil {
+			return nil, err
+		}
+		blobs[k] = blob
+		out.WriteString(k + "=" + blob + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sealed, err := seal(raw, manifest(blobs), "manifest")
+	if err != nil {
+		return nil, err
+	}
+	out.WriteString(manifestPrefix + sealed + "\n")
+	return out.Bytes(), nil
+}
+
+// DecryptDotenv : decrypt a file made by EncryptDotenv back to a plain dotenv
+// file, returning how it was encrypted
+func DecryptDotenv(data []byte, key string) ([]byte, EncryptMode, error) {
+	raw, err := parseKey(key)
+	if err != nil {
+		return nil, EncryptValues, err
+	}
+	if blob, whole := encryptedBlob(data); whole {
+		plain, err := unseal(raw, blob, "")
+		return plain, EncryptFile, err
+	}
+
+	var out bytes.Buffer
+	blobs := make(map[string]string)
+	sealed := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+	for scanner.Scan() {
+		line++
+		if m, ok := strings.CutPrefix(scanner.Text(), manifestPrefix); ok {
+			sealed = m
+			continue
+		}
+		k, v, ok, err := parseLine(scanner.Text())
+		if err != nil {
+			return nil, EncryptValues, fmt.Errorf("line %d: %w", line, err)
+		}
+		if !ok {
+			out.WriteString(scanner.Text() + "\n")
+			continue
+		}
+		if !strings.HasPrefix(v, encPrefix) {
+			return nil, EncryptValues, fmt.Errorf("line %d: %w: %s is not encrypted", line, ErrTampered, k)
+		}
+		plain, err := unseal(raw, v, k)
+		if err != nil {
+			return nil, EncryptValues, fmt.Errorf("line %d: %s: %w", line, k, err)
+		}
+		blobs[k] = v
+		out.WriteString(k + "=" + strconv.Quote(string(plain)) + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, EncryptValues, err
+	}
+	if sealed == "" {
+		return nil, EncryptValues, fmt.Errorf("%w: missing manifest", ErrTampered)
+	}
+	want, err := unseal(raw, sealed, "manifest")
+	if err != nil {
+		return nil, EncryptValues, fmt.Errorf("manifest: %w", err)
+	}
+	if !bytes.Equal(want, manifest(blobs)) {
+		return nil, EncryptValues, fmt.Errorf("%w: values were added, removed or replaced", ErrTampered)
+	}
+	return out.Bytes(), EncryptValues, nil
+}
+
+// readEncryptedFile : read and decrypt path with the key from ks
+func (e *Environment) readEncryptedFile(path string, ks KeySource) (map[string]string, error) {
+	key, err := e.ReadKey(ks)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	plain, _, err := DecryptDotenv(data, key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	values, err := ParseDotenv(bytes.NewReader(plain))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return values, nil
+}
+
+/* LoadEncryptedFile : same as LoadFile for a file encrypted with
+ * EncryptDotenv or `scanenv encrypt`, either per value or as a whole
+ *   env.LoadEncryptedFile(".env.production", env.KeySource{File: ".env.key"})
+ * Unencrypted values and lines added or dropped since the file was encrypted
+ * fail with ErrTampered. Reload reads the key again, so a rotated key file is
+ * picked up.
+ */
+func (e *Environment) LoadEncryptedFile(path string, ks KeySource) error {
+	return e.loadFile(path, func() (map[string]fileValue, error) {
+		values, err := e.readEncryptedFile(path, ks)
+		return atPath(values, path), err
+	})
+}
@@ -0,0 +1,134 @@
+package env_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/taybart/env"
+	"github.com/taybart/env/envtest"
+)
+
+const plainDotenv = `# database
+DB_HOST=db.internal
+DB_PASSWORD="p#ss | word"
+`
+
+func TestEncryptDotenv(t *testing.T) {
+	is := is.New(t)
+	key, err := env.GenerateKey()
+	is.NoErr(err)
+
+	for _, mode := range []env.EncryptMode{env.EncryptValues, env.EncryptFile} {
+		enc, err := env.EncryptDotenv([]byte(plainDotenv), key, mode)
+		is.NoErr(err)
+		is.True(!strings.Contains(string(enc), "db.internal"))
+
+		plain, got, err := env.DecryptDotenv(enc, key)
+		is.NoErr(err)
+		is.Equal(got, mode)
+		values, err := env.ParseDotenv(strings.NewReader(string(plain)))
+		is.NoErr(err)
+		is.Equal(values, map[string]string{"DB_HOST": "db.internal", "DB_PASSWORD": "p#ss | word"})
+
+		other, _ := env.GenerateKey()
+		_, _, err = env.DecryptDotenv(enc, other)
+		is.True(errors.Is(err, env.ErrWrongKey))
+	}
+
+	// keys and comments stay readable per value
+	enc, err := env.EncryptDotenv([]byte(plainDotenv), key, env.EncryptValues)
+	is.NoErr(err)
+	is.True(strings.HasPrefix(string(enc), "# database\nDB_HOST=enc:v1:"))
+
+	// encrypting again only encrypts new plain values
+	again, err := env.EncryptDotenv(append(enc, "NEW=value\n"...), key, env.EncryptValues)
+	is.NoErr(err)
+	lines := strings.Split(string(enc), "\n")
+	is.True(strings.HasPrefix(string(again), strings.Join(lines[:3], "\n")))
+	is.True(!strings.Contains(string(again), "NEW=value"))
+	_, _, err = env.DecryptDotenv(again, key)
+	is.NoErr(err)
+
+	// plain lines can't be slipped into an encrypted file
+	_, _, err = env.DecryptDotenv(append(enc, "DB_HOST=evil.example.com\n"...), key)
+	is.True(errors.Is(err, env.ErrTampered))
+	// nor can encrypted lines be dropped or replayed
+	dropped := strings.Join(append(lines[:1:1], lines[2:]...), "\n")
+	_, _, err = env.DecryptDotenv([]byte(dropped), key)
+	is.True(errors.Is(err, env.ErrTampered))
+	older, err := env.EncryptDotenv([]byte("DB_HOST=old.internal\n"), key, env.EncryptValues)
+	is.NoErr(err)
+	replayed := string(enc) + strings.Split(string(older), "\n")[0] + "\n"
+	_, _, err = env.DecryptDotenv([]byte(replayed), key)
+	is.True(errors.Is(err, env.ErrTampered))
+
+	// swapping encrypted values between keys is detected
+	host := strings.TrimPrefix(lines[1], "DB_HOST=")
+	pass := strings.TrimPrefix(lines[2], "DB_PASSWORD=")
+	swapped := "DB_HOST=" + pass + "\nDB_PASSWORD=" + host + "\n"
+	_, _, err = env.DecryptDotenv([]byte(swapped), key)
+	is.True(errors.Is(err, env.ErrTampered))
+
+	// and so is a flipped byte
+	enc, err = env.EncryptDotenv([]byte(plainDotenv), key, env.EncryptFile)
+	is.NoErr(err)
+	i := len(enc) - 5 // inside the ciphertext, before any base64 padding
+	if enc[i] == 'A' {
+		enc[i] = 'B'
+	} else {
+		enc[i] = 'A'
+	}
+	_, _, err = env.DecryptDotenv(enc, key)
+	is.True(errors.Is(err, env.ErrTampered))
+
+	_, err = env.EncryptDotenv([]byte(plainDotenv), "too short", env.EncryptFile)
+	is.True(err != nil)
+}
+
+func TestLoadEncryptedFile(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	key, err := env.GenerateKey()
+	is.NoErr(err)
+	keyFile := filepath.Join(dir, ".env.key")
+	is.NoErr(os.WriteFile(keyFile, []byte(key+"\n"), 0o600))
+
+	enc, err := env.EncryptDotenv([]byte(plainDotenv), key, env.EncryptValues)
+	is.NoErr(err)
+	path := filepath.Join(dir, ".env.production")
+	is.NoErr(os.WriteFile(path, enc, 0o600))
+
+	e := envtest.New(t, nil)
+	is.NoErr(e.LoadEncryptedFile(path, env.KeySource{File: keyFile}))
+	e.Add([]string{"DB_HOST", "DB_PASSWORD!"})
+	is.Equal(e.Get("DB_PASSWORD"), "p#ss | word")
+	is.Equal(e.ProvenanceOf("DB_HOST"), env.Provenance{Kind: env.FromFile, Location: path})
+
+	// an added plain line fails the load instead of overriding the file
+	tampered := filepath.Join(dir, ".env.tampered")
+	is.NoErr(os.WriteFile(tampered, append(enc, "DB_HOST=evil.example.com\n"...), 0o600))
+	err = envtest.New(t, nil).LoadEncryptedFile(tampered, env.KeySource{File: keyFile})
+	is.True(errors.Is(err, env.ErrTampered))
+
+	// reload decrypts again
+	enc, err = env.EncryptDotenv([]byte("DB_HOST=replica\nDB_PASSWORD=new\n"), key, env.EncryptFile)
+	is.NoErr(err)
+	is.NoErr(os.WriteFile(path, enc, 0o600))
+	is.NoErr(e.Reload())
+	is.Equal(e.Get("DB_HOST"), "replica")
+
+	// the key can come from a variable
+	other, _ := env.GenerateKey()
+	e2 := envtest.New(t, map[string]string{"ENV_KEY": other})
+	err = e2.LoadEncryptedFile(path, env.KeySource{})
+	is.True(errors.Is(err, env.ErrWrongKey))
+	e2.Setenv("ENV_KEY", key)
+	is.NoErr(e2.LoadEncryptedFile(path, env.KeySource{}))
+
+	_, err = envtest.New(t, nil).ReadKey(env.KeySource{Var: "MISSING"})
+	is.True(err != nil)
+}
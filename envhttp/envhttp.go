@@ -0,0 +1,124 @@
+/* Package envhttp serves the state of an env.Environment for debugging, like
+ * expvar does for variables. Secret values are always redacted.
+ *   mux.Handle("/debug/env", envhttp.Handler(nil))
+ * Browsers get an HTML table, ?format=json or an Accept: application/json
+ * header gets JSON.
+ */
+package envhttp
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/taybart/env"
+)
+
+// Var : the state of a declared key
+type Var struct {
+	Name        string  `json:"name"`
+	Spec        string  `json:"spec"`
+	Description string  `json:"description,omitempty"`
+	Value       *string `json:"value"`
+	Secret      bool    `json:"secret,omitempty"`
+	Provenance  string  `json:"provenance"`
+	Error       string  `json:"error,omitempty"`
+}
+
+// State : what the handler serves
+type State struct {
+	Valid      bool      `json:"valid"`
+	Frozen     bool      `json:"frozen"`
+	LastReload time.Time `json:"last_reload,omitempty"`
+	Vars       []Var     `json:"vars"`
+	Warnings   []string  `json:"warnings,omitempty"`
+}
+
+// Snapshot : the current state of e, env.Default() when e is nil
+func Snapshot(e *env.Environment) State {
+	if e == nil {
+		e = env.Default()
+	}
+	report := e.ConfigReport()
+	s := State{
+		Valid:      true,
+		Frozen:     e.IsFrozen(),
+		LastReload: e.LastReload(),
+		Vars:       make([]Var, 0, len(report.Vars)),
+		Warnings:   report.Warnings,
+	}
+	for _, rv := range report.Vars {
+		v := Var{
+			Name:        rv.Spec.Name,
+			Spec:        rv.Spec.String(),
+			Description: rv.Spec.Description,
+			Secret:      rv.Spec.Secret,
+			Provenance:  rv.Provenance.String(),
+		}
+		if rv.Set {
+			val := rv.Value
+			v.Value = &val
+		}
+		if rv.Err != nil {
+			v.Error = rv.Err.Error()
+			s.Valid = false
+		}
+		s.Vars = append(s.Vars, v)
+	}
+	return s
+}
+
+// Handler : serve the state of e, env.Default() when e is nil. The state is
+// read on every request so it follows reloads and SetDefault.
+func Handler(e *env.Environment) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := Snapshot(e)
+		if r.URL.Query().Get("format") == "json" ||
+			strings.Contains(r.Header.Get("Accept"), "application/json") {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			enc.Encode(s)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		page.Execute(w, s)
+	})
+}
+
+var page = template.Must(template.New("env").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>env</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+td.value { font-family: monospace; }
+.error { color: #b00; }
+</style>
+</head>
+<body>
+<h1>env</h1>
+<p>
+{{if .Valid}}valid{{else}}<span class="error">invalid</span>{{end}}
+{{if .Frozen}} &middot; frozen{{end}}
+{{if not .LastReload.IsZero}} &middot; last reload {{.LastReload.Format "2006-01-02 15:04:05 MST"}}{{end}}
+</p>
+<table>
+<tr><th>name</th><th>value</th><th>provenance</th><th>spec</th><th>status</th></tr>
+{{range .Vars}}<tr>
+<td>{{.Name}}{{if .Description}}<br><small>{{.Description}}</small>{{end}}</td>
+<td class="value">{{if .Value}}{{.Value}}{{else}}<i>unset</i>{{end}}</td>
+<td>{{.Provenance}}</td>
+<td><code>{{.Spec}}</code></td>
+<td>{{if .Error}}<span class="error">{{.Error}}</span>{{else}}ok{{end}}</td>
+</tr>
+{{end}}</table>
+{{range .Warnings}}<p class="error">warning: {{.}}</p>
+{{end}}</body>
+</html>
+`))
@@ -0,0 +1,64 @@
+package envhttp_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/taybart/env/envhttp"
+	"github.com/taybart/env/envtest"
+)
+
+func TestHandler(t *testing.T) {
+	is := is.New(t)
+	e := envtest.New(t, map[string]string{
+		"HOST":  "<example.com>",
+		"TOKEN": "1234",
+	})
+	e.DefaultsInProcess(false)
+	e.Add([]string{"HOST # where to connect", "PORT=6969 | int", "TOKEN! | int", "SECURE?"})
+	// break values after Add has validated them
+	e.Setenv("PORT", "nope")
+	e.Setenv("TOKEN", "hunter2")
+	h := envhttp.Handler(e.Environment)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/env?format=json", nil))
+	is.Equal(rec.Header().Get("Content-Type"), "application/json; charset=utf-8")
+	var s envhttp.State
+	is.NoErr(json.Unmarshal(rec.Body.Bytes(), &s))
+	is.True(!s.Valid)
+	is.Equal(len(s.Vars), 4)
+	is.Equal(s.Vars[0].Name, "HOST")
+	is.Equal(s.Vars[0].Description, "where to connect")
+	is.Equal(s.Vars[0].Provenance, "env")
+	is.True(s.Vars[1].Error != "")  // PORT is not an int
+	is.True(s.Vars[2].Value == nil) // SECURE is unset
+	is.Equal(*s.Vars[3].Value, "<redacted>")
+	is.True(!strings.Contains(rec.Body.String(), "hunter2"))
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/env", nil))
+	is.Equal(rec.Header().Get("Content-Type"), "text/html; charset=utf-8")
+	body := rec.Body.String()
+	is.True(strings.Contains(body, "&lt;example.com&gt;")) // values are escaped
+	is.True(strings.Contains(body, "invalid"))
+	is.True(!strings.Contains(body, "hunter2"))
+}
+
+func TestHandlerSecretDefault(t *testing.T) {
+	is := is.New(t)
+	e := envtest.New(t, nil)
+	e.DefaultsInProcess(false)
+	e.Add([]string{"API_KEY!=sk_live_devdefault"})
+	h := envhttp.Handler(e.Environment)
+
+	for _, url := range []string{"/debug/env?format=json", "/debug/env"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", url, nil))
+		is.True(!strings.Contains(rec.Body.String(), "sk_live_devdefault"))
+	}
+	is.Equal(envhttp.Snapshot(e.Environment).Vars[0].Spec, "API_KEY!=<redacted>")
+}
@@ -0,0 +1,76 @@
+package env
+
+import (
+	"sort"
+	"strings"
+)
+
+// EnvironOptions : what Environ passes to a child process. Patterns are
+// either a key or a prefix ending in *, ex. "AWS_*"
+type EnvironOptions struct {
+	// Allow limits the declared keys passed on, all of them when empty
+	Allow []string
+	// Deny removes keys, wins over Allow and Inherit
+	Deny []string
+	// Inherit copies undeclared keys through, ex. "PATH", "LC_*"
+	Inherit []string
+	// StripSecrets drops keys declared as secret (NAME!)
+	StripSecrets bool
+	// Extra is added last and overrides everything else
+	Extra map[string]string
+}
+
+/* Environ : build an environment for exec.Cmd.Env from the declared
+ * variables, including in memory defaults.
+ *   cmd.Env = env.Environ(env.EnvironOptions{
+ *     Inherit:      []string{"PATH", "HOME"},
+ *     StripSecrets: true,
+ *   })
+ */
+func (e *Environment) Environ(opts EnvironOptions) []string {
+	specs := e.reg().specs
+	values := make(map[string]string)
+	for k, v := range e.environ() {
+		if _, declared := specs[k]; !declared && matchKey(opts.Inherit, k) {
+			values[k] = v
+		}
+	}
+	for k, v := range e.declaredValues() {
+		if len(opts.Allow) > 0 && !matchKey(opts.Allow, k) {
+			continue
+		}
+		if opts.StripSecrets && specs[k].Secret {
+			continue
+		}
+		values[k] = v
+	}
+	for k := range values {
+		if matchKey(opts.Deny, k) {
+			delete(values, k)
+		}
+	}
+	for k, v := range opts.Extra {
+		values[k] = v
+	}
+
+	environ := make([]string, 0, len(values))
+	for k, v := range values {
+		environ = append(environ, k+"="+v)
+	}
+	sort.Strings(environ)
+	return environ
+}
+
+// matchKey : if key matches any of patterns, a key or a prefix ending in *
+func matchKey(patterns []string, key string) bool {
+	for _, p := range patterns {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if p == key {
+			return true
+		}
+	}
+	return false
+}
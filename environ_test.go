@@ -0,0 +1,47 @@
+package env_test
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/taybart/env"
+	"github.com/taybart/env/envtest"
+)
+
+func TestEnviron(t *testing.T) {
+	is := is.New(t)
+	e := envtest.New(t, map[string]string{
+		"PATH":        "/bin",
+		"HOME":        "/home/test",
+		"AWS_SECRET":  "shh",
+		"APP_HOST":    "example.com",
+		"APP_TOKEN":   "token",
+		"APP_DEBUG":   "true",
+		"UNDECLARED":  "stray",
+		"LC_ALL":      "C",
+		"LC_COLLATE":  "C",
+		"APP_PROFILE": "dev",
+	})
+	e.DefaultsInProcess(false)
+	e.Add([]string{"APP_HOST", "APP_PORT=8080", "APP_TOKEN!", "APP_DEBUG?", "APP_PROFILE"})
+
+	is.Equal(e.Environ(env.EnvironOptions{
+		Deny:         []string{"APP_DEBUG"},
+		Inherit:      []string{"PATH", "LC_*"},
+		StripSecrets: true,
+		Extra:        map[string]string{"CHILD": "1", "APP_PROFILE": "child"},
+	}), []string{
+		"APP_HOST=example.com",
+		"APP_PORT=8080", // in memory defaults are passed on
+		"APP_PROFILE=child",
+		"CHILD=1",
+		"LC_ALL=C",
+		"LC_COLLATE=C",
+		"PATH=/bin",
+	})
+
+	is.Equal(e.Environ(env.EnvironOptions{Allow: []string{"APP_H*", "APP_TOKEN"}}), []string{
+		"APP_HOST=example.com",
+		"APP_TOKEN=token",
+	})
+}
@@ -1,66 +1,143 @@
 package env
 
 import (
-	"encoding/base64"
-	"encoding/json"
 	"fmt"
-	"os"
-	"regexp"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/taybart/log"
 )
 
-var (
-	keyRE = regexp.MustCompile(`([[:word:]]+)([=?])?(.*)?`)
-	// Optional keys that should be set to zero value
-	optionalKeys map[string]bool
-	defaults     map[string]string
-)
+// Environment : a registry of declared env and the sources it is read from.
+// The package level functions use Default(). It is safe for concurrent use,
+// declarations are copy on write so the getters never lock.
+type Environment struct {
+	source Source
+	// process env variables left out of a hermetic environment, nil otherwise
+	ambient map[string]bool
+
+	regMu    sync.Mutex // serializes registry updates, reads don't lock
+	registry atomic.Pointer[registry]
+
+	usageMu  sync.Mutex
+	reads    sync.Map // key -> *atomic.Int64
+	declared map[string]bool
+
+	frozen atomic.Pointer[snapshot] // nil when not frozen
+
+	filesMu sync.Mutex // serializes loads, reads don't lock
+	// values loaded from files, the source takes precedence over these.
+	// The map is replaced on every load, never modified in place.
+	fileValues  atomic.Pointer[map[string]fileValue]
+	loadedFiles []string
+	// how to read each loaded file again
+	readers map[string]fileReader
+
+	subsMu      sync.Mutex
+	subscribers map[string]map[int]func(old, new string)
+	nextSub     int
+
+	reloadMu   sync.Mutex
+	lastReload time.Time
+}
+
+// New : an empty Environment reading from src, env.OS for the process env
+func New(src Source) *Environment {
+	e := &Environment{
+		source:      src,
+		declared:    make(map[string]bool),
+		readers:     make(map[string]fileReader),
+		subscribers: make(map[string]map[int]func(old, new string)),
+	}
+	e.registry.Store(newRegistry())
+	e.fileValues.Store(&map[string]fileValue{})
+	return e
+}
+
+// Source : returns the source the environment reads from
+func (e *Environment) Source() Source {
+	return e.source
+}
 
-func init() {
-	optionalKeys = make(map[string]bool)
-	defaults = make(map[string]string)
+// Spec : returns the declaration of key
+func (e *Environment) Spec(key string) (Spec, bool) {
+	spec, ok := e.reg().specs[key]
+	return spec, ok
 }
 
-/* Add : environment variables for use later. This is global to the project
- * requred -> NAME
- * with_default -> NAME=taybart
+/* Add : declare environment variables for use later
+ * required -> NAME // must be set and not empty
+ * required_may_be_empty -> NAME* // must be set, may be empty
+ * with_default -> NAME=taybart // default used when NAME is unset
+ * empty_default -> NAME= // defaults to an empty string
  * optional -> NAME? // defaults to zero value
+ * see ParseSpec for the full grammar
  */
-func Add(keys []string) {
-	err := Ensure(keys)
+func (e *Environment) Add(keys []string) {
+	err := e.Ensure(keys)
 	if err != nil {
 		panic(err)
 	}
 }
 
 // Ensure : check that env vars are defined, set default, mark optional
-func Ensure(keys []string) error {
-	if len(keys) == 0 {
-		return nil
-	}
-	missingKeys := []string{}
-	optionalKeys = GetOptional(keys)
-	for _, key := range keys {
-		fkey, val := GetDefault(key) // formatted key and default value
+func (e *Environment) Ensure(keys []string) error {
+	return e.WithPrefix("").Ensure(keys)
+}
 
-		if _, found := os.LookupEnv(fkey); !found {
-			if optionalKeys[fkey] {
+// ensure : declare parsed specs, applying defaults and validation
+func (e *Environment) ensure(parsed []Spec) error {
+	missingKeys := []string{}
+	invalid := []string{}
+	e.warnAmbient(parsed)
+	for _, spec := range parsed {
+		fkey := spec.Name
+		e.declare(fkey)
+		current, found := e.resolve(fkey)
+		applyDefault, optionalUnset := false, false
+		if !found {
+			switch {
+			case spec.HasDefault: // is there a default value?
+				current, found, applyDefault = spec.Default, true, true
+				switch { // optional values use their default quietly
+				case !spec.Optional && e.reg().defaultsInProcess:
+					log.Warnf("Setting %s to default value of %s\n", fkey, shown(spec, current))
+				case !spec.Optional:
+					log.Warnf("Using default value of %s for %s\n", shown(spec, current), fkey)
+				}
+			case spec.Optional:
 				log.Warnf("%s marked optional and not defined\n", fkey)
-				continue
+				optionalUnset = true
+			default:
+				missingKeys = append(missingKeys, fkey)
 			}
-			if val != "" { // is there a default value?
-				log.Warnf("Setting %s to default value of %s\n", fkey, val)
-				os.Setenv(fkey, val)
-				defaults[fkey] = val
-				continue
+		} else if current == "" && !(spec.Optional || spec.AllowEmpty || spec.HasDefault) {
+			missingKeys = append(missingKeys, fkey)
+		}
+
+		var prev string
+		var differs, inProcess bool
+		e.update(func(r *registry) {
+			r.specs[fkey] = spec
+			if applyDefault {
+				r.defaults[fkey] = current
 			}
-			missingKeys = append(missingKeys, key)
+			// was this previously set to something different?
+			prev, differs = r.defaults[fkey]
+			differs = differs && prev != spec.Default && !optionalUnset
+			inProcess = r.defaultsInProcess
+		})
+		if differs {
+			panic(fmt.Sprintf("Differing default value for %s [ %s!=%s ]\n", fkey, shown(spec, prev), shown(spec, spec.Default)))
+		}
+		if setter, ok := e.source.(Setter); ok && applyDefault && inProcess {
+			setter.Set(fkey, current)
 		}
-		// was this previously set to something different?
-		if defaults[fkey] != "" && defaults[fkey] != val {
-			panic(fmt.Sprintf("Differing default value for %s [ %s!=%s ]\n", fkey, defaults[fkey], val))
+		if err := validate(spec, current); found && err != nil {
+			invalid = append(invalid, err.Error())
 		}
 	}
 	for _, key := range missingKeys {
@@ -70,30 +147,55 @@ func Ensure(keys []string) error {
 	if len(missingKeys) > 0 {
 		return fmt.Errorf("set all required environment variables: %v", missingKeys)
 	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("invalid environment variables: %s", strings.Join(invalid, "; "))
+	}
 	return nil
 }
 
-// Has : see if env var defined
-func Has(key string) bool {
-	_, b := os.LookupEnv(key)
+// shown : val quoted for logs, or Redacted when spec is a secret
+func shown(spec Spec, val string) string {
+	if spec.Secret {
+		return Redacted
+	}
+	return strconv.Quote(val)
+}
+
+// DefaultsInProcess : when true (the default) Ensure writes default values to
+// the process env (or any Source that is a Setter) so child processes inherit them. When false
+// defaults are only kept in memory and resolved by the getters.
+func (e *Environment) DefaultsInProcess(set bool) {
+	e.update(func(r *registry) { r.defaultsInProcess = set })
+}
+
+// IsDefault : returns if the value of key comes from its declared or
+// computed default
+func (e *Environment) IsDefault(key string) bool {
+	kind := e.ProvenanceOf(key).Kind
+	return kind == FromDefault || kind == FromComputed
+}
+
+// Has : see if env var defined, an empty string counts as defined
+func (e *Environment) Has(key string) bool {
+	_, b := e.lookup(key)
 	return b
 }
 
 // Is : returns if the variable _is_ the string
-func Is(key, compare string) bool {
-	if val, found := os.LookupEnv(key); found {
+func (e *Environment) Is(key, compare string) bool {
+	if val, found := e.lookup(key); found {
 		return val == compare
 	}
 	return false
 }
 
 // Get : returns the environment value as a string
-func Get(key string) string {
-	if val, found := os.LookupEnv(key); found {
+func (e *Environment) Get(key string) string {
+	if val, found := e.lookup(key); found {
 		return val
 	}
 	log.Warnf("checking optional value %v\n", key)
-	if _, found := optionalKeys[key]; found {
+	if e.reg().specs[key].Optional {
 		return ""
 	}
 
@@ -101,17 +203,24 @@ func Get(key string) string {
 	return ""
 }
 
-// Decode : returns the environment value as base64 decoded bytes
-func Decode(key string) ([]byte, error) {
-	if val, found := os.LookupEnv(key); found {
-		decoded, err := base64.StdEncoding.DecodeString(val)
+// Decode : returns the environment value as decoded bytes, standard base64
+// unless another encoding is passed
+func (e *Environment) Decode(key string, enc ...Encoding) ([]byte, error) {
+	encoding := Base64
+	if len(enc) > 0 {
+		encoding = enc[0]
+	}
+	if val, found := e.lookup(key); found {
+		// cached per encoding, the same key may be decoded more than one way
+		decoded, err := parseCached(e, key+"#"+encoding.String(), val, encoding.decode)
 		if err != nil {
 			return nil, err
 		}
-		return decoded, nil
+		// copy so callers can't modify the cached value
+		return append([]byte(nil), decoded...), nil
 	}
 	log.Warnf("checking for optional %v\n", key)
-	if _, found := optionalKeys[key]; found {
+	if e.reg().specs[key].Optional {
 		return nil, nil
 	}
 
@@ -120,15 +229,15 @@ func Decode(key string) ([]byte, error) {
 }
 
 // Int : returns the key as an int or panics
-func Int(key string) int {
-	if val, found := os.LookupEnv(key); found {
-		converted, err := strconv.Atoi(val)
+func (e *Environment) Int(key string) int {
+	if val, found := e.lookup(key); found {
+		converted, err := parseCached(e, key, val, strconv.Atoi)
 		if err != nil {
 			log.Fatalf("An error occurred in converting the value [%s] retrieved with key [%s] to an int: %s", val, key, err)
 		}
 		return converted
 	}
-	if _, found := optionalKeys[key]; found {
+	if e.reg().specs[key].Optional {
 		return 0
 	}
 
@@ -137,11 +246,11 @@ func Int(key string) int {
 }
 
 // Bool : returns the env var as its value, or false if it doesn't exist
-func Bool(key string) bool {
-	if val, found := os.LookupEnv(key); found {
+func (e *Environment) Bool(key string) bool {
+	if val, found := e.lookup(key); found {
 		return val == "true"
 	}
-	if _, found := optionalKeys[key]; found {
+	if e.reg().specs[key].Optional {
 		return false
 	}
 
@@ -149,45 +258,87 @@ func Bool(key string) bool {
 	return false
 }
 
-// IsSet : returns if the environment variable is set including a blank string
-func IsSet(key string) bool {
-	value, found := os.LookupEnv(key)
+// IsSet : returns if the environment variable is defined and not a blank string
+func (e *Environment) IsSet(key string) bool {
+	value, found := e.lookup(key)
 	if found {
 		return value != ""
 	}
 	return found
 }
 
-// JSON : returns the environment value marshalled to input
-func JSON(key string, input any) error {
-	if val, found := os.LookupEnv(key); found {
-		err := json.Unmarshal([]byte(val), input)
-		if err != nil {
-			return fmt.Errorf("could not unmarshal %s: (value: %+v) %v", key, val, err)
-		}
-		return nil
+// JSON : returns the environment value marshalled to input, see JSONWith for
+// stricter checks
+func (e *Environment) JSON(key string, input any) error {
+	return e.JSONWith(key, input, JSONOptions{})
+}
+
+// lookup : fetch a value from the environment (or the frozen snapshot) and
+// record the read
+func (e *Environment) lookup(key string) (string, bool) {
+	e.recordRead(key)
+	if val, found, ok := e.frozenLookup(key); ok {
+		return val, found
+	}
+	return e.resolve(key)
+}
+
+// resolve : read key from Set, flags, the process env then loaded files, falling
+// back to in memory defaults
+func (e *Environment) resolve(key string) (string, bool) {
+	return e.resolveIn(key, e.fileLayer())
+}
+
+// resolveIn : resolve with a specific file layer
+func (e *Environment) resolveIn(key string, layer map[string]fileValue) (string, bool) {
+	r := e.reg()
+	if val, ok := r.runtime[key]; ok {
+		return val, true
+	}
+	if f, ok := r.flags[key]; ok {
+		return f.value, true
 	}
-	if _, found := optionalKeys[key]; found {
-		return nil
+	if val, found := e.source.Lookup(key); found && !e.writtenDefault(r, key, val) {
+		return val, found
 	}
+	if fv, found := layer[key]; found {
+		return fv.value, found
+	}
+	val, found := r.defaults[key]
+	return val, found
+}
 
-	log.Fatalf("Trying to retrieve uninitialized environment variable: %s, found (should show nothing) %s\n", key, os.Getenv(key))
-	return nil
+// writtenDefault : returns if val, read from the source, is the default
+// Ensure wrote there. Env files still take precedence over those.
+func (e *Environment) writtenDefault(r *registry, key, val string) bool {
+	if !r.defaultsInProcess {
+		return false
+	}
+	_, isSetter := e.source.(Setter)
+	def, hasDefault := r.defaults[key]
+	return isSetter && hasDefault && val == def
 }
 
+// GetDefault : returns the name and default value of a spec
+//
+// Deprecated: use ParseSpec
 func GetDefault(entry string) (key string, defaultValue string) {
-	res := keyRE.FindAllStringSubmatch(entry, -1)
-	return res[0][1], res[0][3]
+	spec, err := ParseSpec(entry)
+	if err != nil {
+		return entry, ""
+	}
+	return spec.Name, spec.Default
 }
 
+// GetOptional : returns which specs are marked optional (NAME?)
+//
+// Deprecated: use ParseSpec
 func GetOptional(keys []string) map[string]bool {
 	optionals := make(map[string]bool)
-	if len(keys) == 0 {
-		return optionals
-	}
 	for _, key := range keys {
-		res := keyRE.FindAllStringSubmatch(key, -1)
-		optionals[res[0][1]] = res[0][2] == "?"
+		if spec, err := ParseSpec(key); err == nil {
+			optionals[spec.Name] = spec.Optional
+		}
 	}
 	return optionals
 }
@@ -3,14 +3,18 @@ package env_test
 import (
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/matryer/is"
 	"github.com/taybart/env"
+	"github.com/taybart/env/envtest"
+	"github.com/taybart/log"
 )
 
 func TestDefault(t *testing.T) {
 	is := is.New(t)
+	envtest.Global(t, nil)
 	// Define key
 	k := "TEST_DEFAULT"
 	v := "default_value"
@@ -20,6 +24,7 @@ func TestDefault(t *testing.T) {
 
 func TestDefaultGuard(t *testing.T) {
 	is := is.New(t)
+	envtest.Global(t, nil)
 	defer func() {
 		// we should panic here
 		is.True(recover() != nil)
@@ -33,6 +38,7 @@ func TestDefaultGuard(t *testing.T) {
 // Test that optionals are set to zero value
 func TestOptionalKey(t *testing.T) {
 	is := is.New(t)
+	envtest.Global(t, nil)
 
 	k := "TEST_OPTIONAL_KEY"
 
@@ -50,18 +56,20 @@ func TestOptionalKey(t *testing.T) {
 
 func TestGet(t *testing.T) {
 	is := is.New(t)
+	e := envtest.Global(t, nil)
 	k := "TestGet"
 	// set var
-	os.Setenv(k, "cool variable")
+	e.Setenv(k, "cool variable")
 	// Should return true since TESTING_ENV is set to true
 	is.True(env.Get(k) == "cool variable")
 }
 
 func TestDecode(t *testing.T) {
 	is := is.New(t)
+	e := envtest.Global(t, nil)
 	k := "TestGet"
 	// set var
-	os.Setenv(k, "Y29vbCB2YXJpYWJsZQ==")
+	e.Setenv(k, "Y29vbCB2YXJpYWJsZQ==")
 	val, err := env.Decode(k)
 	is.NoErr(err)
 	is.True(string(val) == "cool variable")
@@ -70,31 +78,37 @@ func TestDecode(t *testing.T) {
 // TestHas : if value is set env.Has returns true
 func TestHas(t *testing.T) {
 	is := is.New(t)
+	e := envtest.Global(t, nil)
 	// Define key
 	key := "TEST_HAS"
 
 	// set env
-	os.Setenv(key, "this is defined now")
+	e.Setenv(key, "this is defined now")
 	// set
 	is.True(env.Has(key))
 }
 
 func TestIsSet(t *testing.T) {
 	is := is.New(t)
-	// Reset environment after test
-	defer os.Clearenv()
-	os.Setenv("REQUIRED_VAR", "")
+	e := envtest.Global(t, nil)
+	e.Setenv("REQUIRED_VAR", "")
 
 	err := env.Ensure([]string{
-		"REQUIRED_VAR",
+		"REQUIRED_VAR*",
 		"OPTIONAL_VAR?",
 		"DEFAULT_VAR=default",
+		"EMPTY_DEFAULT_VAR=",
 	})
 	is.NoErr(err)
 
 	// Test blank string value
+	is.True(env.Has("REQUIRED_VAR"))
 	is.True(!env.IsSet("REQUIRED_VAR"))
 
+	// Test empty default "EMPTY_DEFAULT_VAR="
+	is.True(env.Has("EMPTY_DEFAULT_VAR"))
+	is.True(!env.IsSet("EMPTY_DEFAULT_VAR"))
+
 	// Test optional var "OPTIONAL_VAR?"
 	is.True(!env.IsSet("OPTIONAL_VAR"))
 
@@ -102,21 +116,33 @@ func TestIsSet(t *testing.T) {
 	is.True(env.IsSet("DEFAULT_VAR"))
 }
 
+func TestRequiredNotEmpty(t *testing.T) {
+	is := is.New(t)
+	e := envtest.Global(t, nil)
+	k := "TEST_REQUIRED_NOT_EMPTY"
+	e.Setenv(k, "")
+
+	is.True(env.Ensure([]string{k}) != nil)
+	is.NoErr(env.Ensure([]string{k + "*"}))
+}
+
 func TestBool(t *testing.T) {
 	is := is.New(t)
+	e := envtest.Global(t, nil)
 
 	// Define key
 	k := "TEST_BOOL"
 
 	// Set env
-	os.Setenv(k, "true")
+	e.Setenv(k, "true")
 	// Should return true since TESTING_ENV is set to true
 	is.True(env.Bool(k))
 }
 
 func TestIs(t *testing.T) {
 	is := is.New(t)
-	os.Setenv("TEST_IS", "testing")
+	e := envtest.Global(t, nil)
+	e.Setenv("TEST_IS", "testing")
 	// Set
 	is.True(env.Is("TEST_IS", "testing"))
 }
@@ -124,12 +150,13 @@ func TestIs(t *testing.T) {
 // Test json interface marshaling
 func TestInterface(t *testing.T) {
 	is := is.New(t)
+	e := envtest.Global(t, nil)
 
 	// Define key
 	k := "TEST_INTERFACE"
 
 	// expected by call to be set, particular value doesn't matter as long as the type is correct
-	os.Setenv(k, `{"key": "val", "other": "sudo su"}`)
+	e.Setenv(k, `{"key": "val", "other": "sudo su"}`)
 
 	// test struct
 	var returned map[string]string
@@ -138,3 +165,84 @@ func TestInterface(t *testing.T) {
 	// Should get the correct value
 	is.True(returned["key"] == "val")
 }
+
+func TestDefaultsInMemory(t *testing.T) {
+	is := is.New(t)
+	e := envtest.Global(t, nil)
+	env.DefaultsInProcess(false)
+
+	k := "TEST_DEFAULTS_IN_MEMORY"
+	env.Add([]string{fmt.Sprintf("%s=8080", k)})
+
+	// not leaked to the process
+	_, found := e.Source.Lookup(k)
+	is.True(!found)
+	// but resolved by the getters
+	is.True(env.Has(k))
+	is.Equal(env.Int(k), 8080)
+	is.True(env.IsDefault(k))
+
+	e.Setenv(k, "9090")
+	is.Equal(env.Int(k), 9090)
+	is.True(!env.IsDefault(k))
+}
+
+func TestEnsureRedactsSecrets(t *testing.T) {
+	is := is.New(t)
+	e := envtest.New(t, map[string]string{"TOKEN": "hunter2"})
+
+	err := e.Ensure([]string{"TOKEN! | oneof(a,b)"})
+	is.True(err != nil)
+	is.True(!strings.Contains(err.Error(), "hunter2"))
+	is.True(strings.Contains(err.Error(), "TOKEN failed validation"))
+
+	func() {
+		defer func() {
+			r := recover()
+			is.True(r != nil)
+			is.True(!strings.Contains(fmt.Sprint(r), "hunter2"))
+		}()
+		e.Add([]string{"TOKEN! | oneof(a,b)"})
+	}()
+
+	// reload reports the same way
+	is.True(!strings.Contains(fmt.Sprint(e.Reload()), "hunter2"))
+}
+
+func TestDefaultLogRedactsSecrets(t *testing.T) {
+	is := is.New(t)
+	envtest.Global(t, nil) // serializes tests touching the log output
+	var out strings.Builder
+	log.SetOutputWriter(&out)
+	t.Cleanup(func() { log.SetOutputWriter(os.Stdout) })
+
+	e := envtest.New(t, nil)
+	e.Add([]string{"API_KEY!=sk_live_devdefault", "LEVEL=info"})
+	e.DefaultsInProcess(false)
+	e.Add([]string{"OTHER_KEY!=sk_live_other"})
+	func() {
+		defer func() {
+			r := recover()
+			is.True(r != nil)
+			is.True(!strings.Contains(fmt.Sprint(r), "sk_live"))
+		}()
+		e.Add([]string{"API_KEY!=sk_live_changed"})
+	}()
+
+	is.True(strings.Contains(out.String(), `"info"`))
+	is.True(strings.Contains(out.String(), env.Redacted))
+	is.True(!strings.Contains(out.String(), "sk_live"))
+}
+
+func TestConfigReportRedactsSecrets(t *testing.T) {
+	is := is.New(t)
+	e := envtest.New(t, nil)
+	e.Add([]string{"API_KEY!=sk_live_devdefault", "LEVEL=info"})
+
+	report := e.ConfigReport()
+	for _, v := range report.Vars {
+		is.True(!strings.Contains(v.Spec.String(), "sk_live"))
+	}
+	is.Equal(report.Vars[1].Spec.Default, "info")
+	is.True(!strings.Contains(report.String(), "sk_live"))
+}
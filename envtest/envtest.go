@@ -0,0 +1,80 @@
+// Package envtest provides isolated environments for testing code that uses
+// github.com/taybart/env without touching the process env.
+package envtest
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/taybart/env"
+)
+
+// serializes tests that replace env.Default()
+var globalMu sync.Mutex
+
+// Env : an Environment with its own registry, backed by an in memory source
+type Env struct {
+	*env.Environment
+	// Source holds the variables the environment sees
+	Source *env.MapSource
+	t      testing.TB
+}
+
+// New : a fresh Environment that only sees values. Nothing touches the
+// process env or the package level registry, so tests can run in parallel.
+func New(t testing.TB, values map[string]string) *Env {
+	t.Helper()
+	src := env.NewMapSource(values)
+	e := &Env{Environment: env.New(src), Source: src, t: t}
+	t.Cleanup(e.Unfreeze)
+	return e
+}
+
+// Global : like New, but also installs the Environment as env.Default() for
+// code that uses the package level functions. The previous default is
+// restored when the test ends. Tests using Global run one at a time.
+func Global(t testing.TB, values map[string]string) *Env {
+	t.Helper()
+	e := New(t, values)
+	globalMu.Lock()
+	previous := env.SetDefault(e.Environment)
+	t.Cleanup(func() {
+		env.SetDefault(previous)
+		globalMu.Unlock()
+	})
+	return e
+}
+
+// Setenv : set key in the fake source
+func (e *Env) Setenv(key, value string) {
+	e.Source.Set(key, value)
+}
+
+// Unsetenv : remove key from the fake source
+func (e *Env) Unsetenv(key string) {
+	e.Source.Unset(key)
+}
+
+// RequireDeclared : fail the test if any of keys was not declared
+func (e *Env) RequireDeclared(keys ...string) {
+	e.t.Helper()
+	for _, key := range keys {
+		if _, ok := e.Spec(key); !ok {
+			e.t.Fatalf("envtest: %s was not declared", key)
+		}
+	}
+}
+
+// RequireDefault : fail the test if key was not declared with default want
+func (e *Env) RequireDefault(key, want string) {
+	e.t.Helper()
+	spec, ok := e.Spec(key)
+	switch {
+	case !ok:
+		e.t.Fatalf("envtest: %s was not declared", key)
+	case !spec.HasDefault:
+		e.t.Fatalf("envtest: %s has no default, want %q", key, want)
+	case spec.Default != want:
+		e.t.Fatalf("envtest: %s defaults to %q, want %q", key, spec.Default, want)
+	}
+}
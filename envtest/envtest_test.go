@@ -0,0 +1,50 @@
+package envtest_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/taybart/env"
+	"github.com/taybart/env/envtest"
+)
+
+func TestNewIsIsolated(t *testing.T) {
+	for _, port := range []string{"80", "443"} {
+		port := port
+		t.Run(port, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+			e := envtest.New(t, map[string]string{"PORT": port})
+			e.Add([]string{"PORT | int", "HOST=localhost"})
+
+			is.Equal(e.Get("PORT"), port)
+			e.RequireDeclared("PORT", "HOST")
+			e.RequireDefault("HOST", "localhost")
+
+			// defaults land in the fake source, not the process
+			_, found := os.LookupEnv("HOST")
+			is.True(!found)
+			host, _ := e.Source.Lookup("HOST")
+			is.Equal(host, "localhost")
+		})
+	}
+}
+
+func TestGlobal(t *testing.T) {
+	is := is.New(t)
+	before := env.Default()
+	t.Run("replaced", func(t *testing.T) {
+		e := envtest.Global(t, map[string]string{"ENVTEST_GLOBAL": "yes"})
+		env.Add([]string{"ENVTEST_GLOBAL"})
+		is.True(env.Is("ENVTEST_GLOBAL", "yes"))
+		e.RequireDeclared("ENVTEST_GLOBAL")
+
+		e.Setenv("ENVTEST_GLOBAL", "no")
+		is.True(env.Is("ENVTEST_GLOBAL", "no"))
+	})
+	// restored after the test
+	is.True(env.Default() == before)
+	_, declared := env.Default().Spec("ENVTEST_GLOBAL")
+	is.True(!declared)
+}
@@ -0,0 +1,92 @@
+package env
+
+import (
+	"flag"
+	"strings"
+)
+
+// envFlag : flag.Value backed by a spec
+type envFlag struct {
+	env   *Environment
+	name  string
+	spec  Spec
+	value string
+}
+
+func (f *envFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.value
+}
+
+func (f *envFlag) Set(val string) error {
+	if err := validate(f.spec, val); err != nil {
+		return err
+	}
+	f.value = val
+	f.env.update(func(r *registry) {
+		r.flags[f.spec.Name] = flagValue{name: f.name, value: val}
+	})
+	return nil
+}
+
+// IsBoolFlag : allows -debug instead of -debug=true for specs validated as bool
+func (f *envFlag) IsBoolFlag() bool {
+	for _, v := range f.spec.Validators {
+		if v.Name == "bool" {
+			return true
+		}
+	}
+	return false
+}
+
+// FlagName : the flag name for key, LOG_LEVEL -> log-level
+func FlagName(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "_", "-")
+}
+
+/* BindFlags : register a flag on fs for every spec in keys, or every key
+ * declared so far when keys is nil. Values given on the command line take
+ * precedence over the environment, which takes precedence over defaults.
+ *
+ * To let required values come from either flags or env, bind before
+ * declaring:
+ *   env.BindFlags(flag.CommandLine, keys)
+ *   flag.Parse()
+ *   env.Add(keys)
+ */
+func (e *Environment) BindFlags(fs *flag.FlagSet, keys []string) error {
+	toBind := []Spec{}
+	if keys == nil {
+		for _, spec := range e.reg().specs {
+			toBind = append(toBind, spec)
+		}
+	}
+	for _, key := range keys {
+		spec, err := ParseSpec(key)
+		if err != nil {
+			return err
+		}
+		toBind = append(toBind, spec)
+	}
+	for _, spec := range toBind {
+		name := FlagName(spec.Name)
+		if fs.Lookup(name) != nil {
+			continue
+		}
+		usage := spec.Description
+		if usage == "" {
+			usage = "sets " + spec.Name
+		}
+		f := &envFlag{env: e, name: name, spec: spec}
+		fs.Var(f, name, usage)
+		switch {
+		case spec.HasDefault && spec.Secret:
+			fs.Lookup(name).DefValue = Redacted
+		case spec.HasDefault:
+			fs.Lookup(name).DefValue = spec.Default
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package env_test
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/taybart/env"
+	"github.com/taybart/env/envtest"
+)
+
+func TestBindFlags(t *testing.T) {
+	is := is.New(t)
+	e := envtest.Global(t, nil)
+	e.Setenv("TEST_FLAGS_HOST", "from.env")
+	e.Setenv("TEST_FLAGS_USER", "env_user")
+
+	keys := []string{
+		"TEST_FLAGS_HOST # host to bind",
+		"TEST_FLAGS_PORT=8080 | int",
+		"TEST_FLAGS_USER",
+		"TEST_FLAGS_TOKEN",
+		"TEST_FLAGS_DEBUG? | bool",
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	is.NoErr(env.BindFlags(fs, keys))
+	is.Equal(fs.Lookup("test-flags-host").Usage, "host to bind")
+	is.Equal(fs.Lookup("test-flags-port").DefValue, "8080")
+
+	// validators run on flags too
+	is.True(fs.Parse([]string{"-test-flags-port=nope"}) != nil)
+
+	is.NoErr(fs.Parse([]string{"-test-flags-host=from.flag", "-test-flags-token=secret", "-test-flags-debug"}))
+	// required values can come from flags
+	env.Add(keys)
+
+	// flag > env > default
+	is.Equal(env.Get("TEST_FLAGS_HOST"), "from.flag")
+	is.Equal(env.Get("TEST_FLAGS_USER"), "env_user")
+	is.Equal(env.Int("TEST_FLAGS_PORT"), 8080)
+	is.True(env.Bool("TEST_FLAGS_DEBUG"))
+
+	is.Equal(env.ProvenanceOf("TEST_FLAGS_HOST").String(), "flag -test-flags-host")
+	is.Equal(env.ProvenanceOf("TEST_FLAGS_USER").Kind, env.FromEnv)
+	is.Equal(env.ProvenanceOf("TEST_FLAGS_PORT").Kind, env.FromDefault)
+	is.Equal(env.ProvenanceOf("TEST_FLAGS_NOPE").Kind, env.FromUnset)
+}
+
+func TestBindFlagsRedactsSecrets(t *testing.T) {
+	is := is.New(t)
+	e := env.New(env.NewMapSource(nil))
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	is.NoErr(e.BindFlags(fs, []string{"TEST_FLAGS_KEY!=hunter2"}))
+	is.Equal(fs.Lookup("test-flags-key").DefValue, env.Redacted)
+
+	var help bytes.Buffer
+	fs.SetOutput(&help)
+	fs.PrintDefaults()
+	is.True(!strings.Contains(help.String(), "hunter2"))
+}
@@ -0,0 +1,164 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrFrozen is returned when declaring or setting env after Freeze
+	ErrFrozen = errors.New("environment is frozen")
+	// ErrDrift is returned by CheckDrift when the process env no longer
+	// matches the frozen snapshot
+	ErrDrift = errors.New("environment drifted from frozen snapshot")
+)
+
+// Drift : a declared key whose process value differs from the frozen one
+type Drift struct {
+	Key     string
+	Frozen  string
+	Current string
+	// Unset is true when the key was removed from the process env
+	Unset bool
+	// Secret keys have their values redacted by String
+	Secret bool
+}
+
+func (d Drift) String() string {
+	frozen, current := strconv.Quote(d.Frozen), strconv.Quote(d.Current)
+	if d.Secret {
+		frozen, current = Redacted, Redacted
+	}
+	if d.Unset {
+		return fmt.Sprintf("%s was unset (frozen as %s)", d.Key, frozen)
+	}
+	return fmt.Sprintf("%s changed from %s to %s", d.Key, frozen, current)
+}
+
+// snapshot : the values getters read while frozen, never modified
+type snapshot struct {
+	values map[string]string
+	parsed sync.Map // parsed value cache by key
+	// the layers below and above the env files when frozen, so a reload
+	// only swaps the files
+	defaults, above map[string]string
+}
+
+// newSnapshot : freeze defaults and the layers above files over files
+func newSnapshot(defaults map[string]string, files map[string]fileValue, above map[string]string) *snapshot {
+	values := make(map[string]string, len(defaults)+len(files)+len(above))
+	for k, v := range defaults {
+		values[k] = v
+	}
+	for k, fv := range files {
+		values[k] = fv.value
+	}
+	for k, v := range above {
+		values[k] = v
+	}
+	return &snapshot{values: values, defaults: defaults, above: above}
+}
+
+// Freeze : snapshot the current environment, all getters will read from the
+// snapshot from now on. Call once Add/Ensure have validated the env.
+func (e *Environment) Freeze() error {
+	if !e.frozen.CompareAndSwap(nil, newSnapshot(e.reg().defaults, e.fileLayer(), e.aboveFiles())) {
+		return ErrFrozen
+	}
+	return nil
+}
+
+// Unfreeze : go back to reading the live process environment
+func (e *Environment) Unfreeze() {
+	e.frozen.Store(nil)
+}
+
+// IsFrozen : returns if getters are being served from a snapshot
+func (e *Environment) IsFrozen() bool {
+	return e.frozen.Load() != nil
+}
+
+// Drifted : returns declared keys that changed in the process env since Freeze
+func (e *Environment) Drifted() []Drift {
+	frozen := e.frozen.Load()
+	if frozen == nil {
+		return nil
+	}
+	e.usageMu.Lock()
+	keys := make([]string, 0, len(e.declared))
+	for k := range e.declared {
+		keys = append(keys, k)
+	}
+	e.usageMu.Unlock()
+	sort.Strings(keys)
+
+	drift := []Drift{}
+	specs := e.reg().specs
+	for _, k := range keys {
+		was, wasSet := frozen.values[k]
+		now, isSet := e.resolve(k)
+		secret := specs[k].Secret
+		switch {
+		case wasSet && !isSet:
+			drift = append(drift, Drift{Key: k, Frozen: was, Unset: true, Secret: secret})
+		case isSet && now != was:
+			drift = append(drift, Drift{Key: k, Frozen: was, Current: now, Secret: secret})
+		}
+	}
+	return drift
+}
+
+// CheckDrift : returns ErrDrift describing every drifted key, nil if the
+// process env still matches the snapshot
+func (e *Environment) CheckDrift() error {
+	drift := e.Drifted()
+	if len(drift) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(drift))
+	for i, d := range drift {
+		msgs[i] = d.String()
+	}
+	return fmt.Errorf("%w: %s", ErrDrift, strings.Join(msgs, ", "))
+}
+
+// frozenLookup : ok is false if the environment is not frozen
+func (e *Environment) frozenLookup(key string) (val string, found bool, ok bool) {
+	frozen := e.frozen.Load()
+	if frozen == nil {
+		return "", false, false
+	}
+	val, found = frozen.values[key]
+	return val, found, true
+}
+
+// parsedEntry : a cached conversion of raw
+type parsedEntry struct {
+	raw string
+	v   any
+}
+
+// parseCached : convert val with fn, caching the result while frozen
+func parseCached[T any](e *Environment, key, val string, fn func(string) (T, error)) (T, error) {
+	frozen := e.frozen.Load()
+	if frozen != nil {
+		if cached, ok := frozen.parsed.Load(key); ok && cached.(parsedEntry).raw == val {
+			if v, ok := cached.(parsedEntry).v.(T); ok {
+				return v, nil
+			}
+		}
+	}
+
+	v, err := fn(val)
+	if err != nil {
+		return v, err
+	}
+	if frozen != nil {
+		frozen.parsed.Store(key, parsedEntry{raw: val, v: v})
+	}
+	return v, nil
+}
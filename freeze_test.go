@@ -0,0 +1,56 @@
+package env_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/taybart/env"
+	"github.com/taybart/env/envtest"
+)
+
+func TestFreeze(t *testing.T) {
+	is := is.New(t)
+	e := envtest.Global(t, nil)
+	k := "TEST_FREEZE"
+	e.Setenv(k, "42")
+	env.Add([]string{k})
+
+	is.NoErr(env.Freeze())
+	defer env.Unfreeze()
+	is.True(env.IsFrozen())
+	is.NoErr(env.CheckDrift())
+
+	e.Setenv(k, "69")
+	// still served from the snapshot
+	is.Equal(env.Int(k), 42)
+	is.Equal(env.Get(k), "42")
+
+	drift := env.Drifted()
+	is.Equal(len(drift), 1)
+	is.Equal(drift[0].Key, k)
+	is.Equal(drift[0].Current, "69")
+	is.True(errors.Is(env.CheckDrift(), env.ErrDrift))
+
+	// no new declarations once frozen
+	is.True(errors.Is(env.Ensure([]string{"TEST_FREEZE_LATE=1"}), env.ErrFrozen))
+
+	env.Unfreeze()
+	is.Equal(env.Int(k), 69)
+}
+
+func TestDriftRedactsSecrets(t *testing.T) {
+	is := is.New(t)
+	e := envtest.New(t, map[string]string{"TOKEN": "hunter2", "LEVEL": "info"})
+	e.Add([]string{"TOKEN!", "LEVEL"})
+	is.NoErr(e.Freeze())
+
+	e.Setenv("TOKEN", "hunter3")
+	e.Setenv("LEVEL", "debug")
+	err := e.CheckDrift()
+	is.True(errors.Is(err, env.ErrDrift))
+	is.True(!strings.Contains(err.Error(), "hunter"))
+	is.True(strings.Contains(err.Error(), `LEVEL changed from "info" to "debug"`))
+	is.True(strings.Contains(err.Error(), "TOKEN changed from <redacted> to <redacted>"))
+}
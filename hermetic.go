@@ -0,0 +1,81 @@
+package env
+
+import (
+	"sort"
+
+	"github.com/taybart/log"
+)
+
+// HermeticConfig : everything a hermetic Environment can see
+type HermeticConfig struct {
+	// Values the environment starts with
+	Values map[string]string
+	// Files loaded in order like LoadFile
+	Files []string
+	// Allow passes these process env variables through, keys or prefixes
+	// ending in *, ex. "PATH", "LC_*"
+	Allow []string
+}
+
+/* NewHermetic : an Environment isolated from the process env, a stray AWS_*
+ * or DEBUG in a developer's shell can't change its behavior
+ *   e, err := env.NewHermetic(env.HermeticConfig{
+ *     Values: map[string]string{"PORT": "8080"},
+ *     Files:  []string{"testdata/.env"},
+ *     Allow:  []string{"HOME", "TMPDIR"},
+ *   })
+ * Values take precedence over allowed variables. Defaults are only written to
+ * the environment's own source, never to the process env. Ensure warns about
+ * declared keys that are set in the process env but were left out.
+ */
+func NewHermetic(cfg HermeticConfig) (*Environment, error) {
+	values := make(map[string]string)
+	ambient := make(map[string]bool)
+	for k, v := range OS.Environ() {
+		if matchKey(cfg.Allow, k) {
+			values[k] = v
+		} else {
+			ambient[k] = true
+		}
+	}
+	for k, v := range cfg.Values {
+		values[k] = v
+		delete(ambient, k)
+	}
+
+	e := New(NewMapSource(values))
+	e.ambient = ambient
+	for _, path := range cfg.Files {
+		if err := e.LoadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// IsHermetic : returns if the environment was made with NewHermetic
+func (e *Environment) IsHermetic() bool {
+	return e.ambient != nil
+}
+
+// IgnoredAmbient : declared keys that are set in the process env but were
+// left out of a hermetic environment
+func (e *Environment) IgnoredAmbient() []string {
+	ignored := []string{}
+	for k := range e.reg().specs {
+		if e.ambient[k] {
+			ignored = append(ignored, k)
+		}
+	}
+	sort.Strings(ignored)
+	return ignored
+}
+
+// warnAmbient : report declared keys the hermetic environment ignored
+func (e *Environment) warnAmbient(parsed []Spec) {
+	for _, spec := range parsed {
+		if e.ambient[spec.Name] {
+			log.Warnf("%s is set in the process env but ignored by the hermetic environment\n", spec.Name)
+		}
+	}
+}
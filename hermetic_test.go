@@ -0,0 +1,50 @@
+package env_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/taybart/env"
+)
+
+func TestHermetic(t *testing.T) {
+	is := is.New(t)
+	t.Setenv("HERMETIC_HOME", "/home/dev")
+	t.Setenv("HERMETIC_LC_ALL", "C")
+	t.Setenv("AWS_PROFILE", "personal")
+	t.Setenv("DEBUG", "true")
+	t.Setenv("PORT", "1234")
+
+	dotenv := filepath.Join(t.TempDir(), ".env")
+	is.NoErr(os.WriteFile(dotenv, []byte("DB_HOST=localhost\n"), 0o600))
+
+	e, err := env.NewHermetic(env.HermeticConfig{
+		Values: map[string]string{"PORT": "8080"},
+		Files:  []string{dotenv},
+		Allow:  []string{"HERMETIC_HOME", "HERMETIC_LC_*"},
+	})
+	is.NoErr(err)
+	is.True(e.IsHermetic())
+	is.True(!env.Default().IsHermetic())
+
+	is.NoErr(e.Ensure([]string{"PORT | int", "DB_HOST", "HERMETIC_HOME", "HERMETIC_LC_ALL", "DEBUG=false", "AWS_PROFILE?"}))
+	is.Equal(e.Int("PORT"), 8080)           // explicit values win
+	is.Equal(e.Get("DB_HOST"), "localhost") // files are loaded
+	is.Equal(e.Get("HERMETIC_HOME"), "/home/dev")
+	is.Equal(e.Get("HERMETIC_LC_ALL"), "C")
+	is.Equal(e.Get("DEBUG"), "false") // the ambient value is ignored
+	is.True(!e.Has("AWS_PROFILE"))
+	is.Equal(e.IgnoredAmbient(), []string{"AWS_PROFILE", "DEBUG"})
+	is.Equal(e.ConfigReport().Warnings, []string{
+		"AWS_PROFILE is set in the process env but ignored by the hermetic environment",
+		"DEBUG is set in the process env but ignored by the hermetic environment",
+	})
+
+	// defaults stay out of the process env
+	is.Equal(os.Getenv("DEBUG"), "true")
+
+	_, err = env.NewHermetic(env.HermeticConfig{Files: []string{"missing.env"}})
+	is.True(err != nil)
+}
@@ -0,0 +1,271 @@
+package env
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/taybart/log"
+)
+
+// JSONOptions : checks JSONWith runs before unmarshalling
+type JSONOptions struct {
+	// DisallowUnknownFields rejects object keys that match no struct field,
+	// catching typos that would otherwise decode to zero values
+	DisallowUnknownFields bool
+	// RequireFields rejects objects missing a field tagged `env:",required"`
+	RequireFields bool
+}
+
+// JSONWith : same as JSON with stricter checks. Errors point to the JSON
+// path and byte offset of the problem, ex. $.db.port (offset 27)
+func (e *Environment) JSONWith(key string, input any, opts JSONOptions) error {
+	if val, found := e.lookup(key); found {
+		return unmarshal(e.reg().specs[key], key, []byte(val), input, opts)
+	}
+	if e.reg().specs[key].Optional {
+		return nil
+	}
+
+	log.Fatalf("Trying to retrieve uninitialized environment variable: %s\n", key)
+	return nil
+}
+
+// unmarshal : unmarshal data into input, the value is left out of errors for
+// secrets
+func unmarshal(spec Spec, key string, data []byte, input any, opts JSONOptions) error {
+	fail := func(err error) error {
+		if spec.Secret {
+			return fmt.Errorf("could not unmarshal %s: (value: %s) %v", key, Redacted, err)
+		}
+		return fmt.Errorf("could not unmarshal %s: (value: %s) %v", key, data, err)
+	}
+	if opts.DisallowUnknownFields || opts.RequireFields {
+		t := reflect.TypeOf(input)
+		if t != nil && t.Kind() == reflect.Pointer {
+			w := jsonWalker{dec: json.NewDecoder(bytes.NewReader(data)), data: data, opts: opts}
+			w.dec.UseNumber()
+			if err := w.value(t.Elem(), "$"); err != nil {
+				return fail(err)
+			}
+		}
+	}
+	err := json.Unmarshal(data, input)
+	var syntax *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntax) && spec.Secret:
+		// the message can quote part of the value
+		return fail(fmt.Errorf("invalid JSON (offset %d)", syntax.Offset))
+	case errors.As(err, &syntax):
+		return fail(fmt.Errorf("%s (offset %d)", syntax, syntax.Offset))
+	case errors.As(err, &typeErr):
+		path := "$"
+		if typeErr.Field != "" {
+			path += "." + typeErr.Field
+		}
+		return fail(fmt.Errorf("cannot use JSON %s as %s at %s (offset %d)", typeErr.Value, typeErr.Type, path, typeErr.Offset))
+	case err != nil:
+		return fail(err)
+	}
+	return nil
+}
+
+// jsonField : a struct field as seen by encoding/json
+type jsonField struct {
+	name     string
+	typ      reflect.Type
+	required bool
+}
+
+// jsonFields : the fields of struct type t, embedded structs are inlined
+func jsonFields(t reflect.Type) []jsonField {
+	fields := []jsonField{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			fields = append(fields, jsonFields(ft)...)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		_, required := envTag(f)
+		fields = append(fields, jsonField{name: name, typ: f.Type, required: required})
+	}
+	return fields
+}
+
+// jsonWalker : walks JSON tokens alongside a Go type to find unknown and
+// missing fields with their path and offset
+type jsonWalker struct {
+	dec  *json.Decoder
+	data []byte
+	opts JSONOptions
+}
+
+// value : check the next JSON value against t
+func (w *jsonWalker) value(t reflect.Type, path string) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	tok, err := w.dec.Token()
+	if err != nil {
+		return w.syntax(err)
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return nil
+	}
+	switch {
+	case delim == '{' && t.Kind() == reflect.Struct:
+		return w.object(t, path)
+	case delim == '{' && t.Kind() == reflect.Map:
+		for w.dec.More() {
+			key, err := w.key()
+			if err != nil {
+				return err
+			}
+			if err := w.value(t.Elem(), path+"."+key); err != nil {
+				return err
+			}
+		}
+	case delim == '[' && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array):
+		for i := 0; w.dec.More(); i++ {
+			if err := w.value(t.Elem(), path+"["+strconv.Itoa(i)+"]"); err != nil {
+				return err
+			}
+		}
+	default: // type mismatches are reported by json.Unmarshal
+		return w.skip(1)
+	}
+	_, err = w.dec.Token() // closing delimiter
+	return w.syntax(err)
+}
+
+// object : check the fields of an object against struct type t
+func (w *jsonWalker) object(t reflect.Type, path string) error {
+	fields := jsonFields(t)
+	seen := make(map[string]bool)
+	for w.dec.More() {
+		offset := w.next()
+		key, err := w.key()
+		if err != nil {
+			return err
+		}
+		// same matching as encoding/json, exact then case insensitive
+		var field *jsonField
+		for i := range fields {
+			if fields[i].name == key {
+				field = &fields[i]
+				break
+			}
+		}
+		for i := range fields {
+			if field == nil && strings.EqualFold(fields[i].name, key) {
+				field = &fields[i]
+			}
+		}
+		if field == nil {
+			if w.opts.DisallowUnknownFields {
+				return fmt.Errorf("unknown field at %s.%s (offset %d)", path, key, offset)
+			}
+			if err := w.skipValue(); err != nil {
+				return err
+			}
+			continue
+		}
+		seen[field.name] = true
+		if err := w.value(field.typ, path+"."+key); err != nil {
+			return err
+		}
+	}
+	offset := w.next()
+	if _, err := w.dec.Token(); err != nil { // closing }
+		return w.syntax(err)
+	}
+	if w.opts.RequireFields {
+		for _, f := range fields {
+			if f.required && !seen[f.name] {
+				return fmt.Errorf("missing required field %s.%s (offset %d)", path, f.name, offset)
+			}
+		}
+	}
+	return nil
+}
+
+// next : offset of the next token, the decoder offset is at the end of the
+// previous one
+func (w *jsonWalker) next() int64 {
+	offset := w.dec.InputOffset()
+	for offset < int64(len(w.data)) && strings.IndexByte(" \t\r\n,:", w.data[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+// key : the next object key
+func (w *jsonWalker) key() (string, error) {
+	tok, err := w.dec.Token()
+	if err != nil {
+		return "", w.syntax(err)
+	}
+	key, _ := tok.(string)
+	return key, nil
+}
+
+// skipValue : consume the next value
+func (w *jsonWalker) skipValue() error {
+	tok, err := w.dec.Token()
+	if err != nil {
+		return w.syntax(err)
+	}
+	if d, ok := tok.(json.Delim); ok && (d == '{' || d == '[') {
+		return w.skip(1)
+	}
+	return nil
+}
+
+// skip : consume tokens until depth open delimiters are closed
+func (w *jsonWalker) skip(depth int) error {
+	for depth > 0 {
+		tok, err := w.dec.Token()
+		if err != nil {
+			return w.syntax(err)
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	return nil
+}
+
+// syntax : add the offset to errors from the decoder
+func (w *jsonWalker) syntax(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("%v (offset %d)", err, w.dec.InputOffset())
+}
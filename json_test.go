@@ -0,0 +1,62 @@
+package env_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/taybart/env"
+	"github.com/taybart/env/envtest"
+)
+
+type dbConfig struct {
+	Host     string `json:"host" env:",required"`
+	Port     int    `json:"port"`
+	Replicas []struct {
+		Host string `json:"host" env:",required"`
+	} `json:"replicas"`
+}
+
+func TestJSONWith(t *testing.T) {
+	is := is.New(t)
+	e := envtest.New(t, map[string]string{
+		"OK":             `{"host": "db", "port": 5432, "replicas": [{"host": "r0"}]}`,
+		"TYPO":           `{"host": "db", "prot": 5432}`,
+		"MISSING":        `{"port": 5432}`,
+		"MISSING_NESTED": `{"host": "db", "replicas": [{"host": "r0"}, {}]}`,
+		"NESTED":         `{"host": "db", "replicas": [{"host": "r0", "hots": "x"}]}`,
+		"BAD_TYPE":       `{"host": "db", "port": "5432"}`,
+		"SECRET":         `{"host": "hunter2", "port": "5432"}`,
+		"BROKEN":         `{"host": "hunter2",}`,
+	})
+	e.Add([]string{"OK", "TYPO", "MISSING", "MISSING_NESTED", "NESTED", "BAD_TYPE", "SECRET!", "BROKEN!"})
+	strict := env.JSONOptions{DisallowUnknownFields: true, RequireFields: true}
+
+	var cfg dbConfig
+	is.NoErr(e.JSONWith("OK", &cfg, strict))
+	is.Equal(cfg.Replicas[0].Host, "r0")
+
+	// plain JSON still ignores typos
+	is.NoErr(e.JSON("TYPO", &cfg))
+
+	errContains := func(err error, want string) {
+		t.Helper()
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("error %v does not contain %q", err, want)
+		}
+	}
+	errContains(e.JSONWith("TYPO", &cfg, strict), "unknown field at $.prot (offset 15)")
+	errContains(e.JSONWith("NESTED", &cfg, strict), "unknown field at $.replicas[0].hots")
+	errContains(e.JSONWith("MISSING", &cfg, env.JSONOptions{RequireFields: true}), "missing required field $.host")
+	errContains(e.JSONWith("MISSING_NESTED", &cfg, env.JSONOptions{RequireFields: true}), "missing required field $.replicas[1].host (offset 45)")
+
+	errContains(e.JSON("BAD_TYPE", &cfg), "cannot use JSON string as int at $.port (offset 29)")
+
+	// secrets never show up in errors
+	err := e.JSON("SECRET", &cfg)
+	errContains(err, env.Redacted)
+	is.True(!strings.Contains(err.Error(), "hunter2"))
+	err = e.JSON("BROKEN", &cfg)
+	errContains(err, "invalid JSON (offset")
+	is.True(!strings.Contains(err.Error(), "hunter2"))
+}
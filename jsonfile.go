@@ -0,0 +1,104 @@
+package env
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/* LoadJSONFile : load a JSON config file as a fallback for the process env,
+ * like LoadFile. Nested keys map onto env names
+ *   {"db": {"host": "x", "maxConns": 10}, "replicas": [{"host": "y"}]}
+ *   DB_HOST=x DB_MAX_CONNS=10 REPLICAS_0_HOST=y
+ * Arrays use the numbering Indexed reads and null is skipped. Values keep
+ * their JSON path, so ProvenanceOf and ConfigReport point at both the file
+ * and the path.
+ */
+func (e *Environment) LoadJSONFile(path string) error {
+	return e.loadFile(path, func() (map[string]fileValue, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		values, err := flattenJSON(data, path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return values, nil
+	})
+}
+
+// flattenJSON : the values of a JSON object keyed by env name
+func flattenJSON(data []byte, path string) (map[string]fileValue, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var root any
+	if err := dec.Decode(&root); err != nil {
+		return nil, err
+	}
+	if _, ok := root.(map[string]any); !ok {
+		return nil, fmt.Errorf("expected a JSON object at the top level")
+	}
+	values := make(map[string]fileValue)
+	var walk func(v any, key, jsonPath string) error
+	walk = func(v any, key, jsonPath string) error {
+		var val string
+		switch v := v.(type) {
+		case map[string]any:
+			// sorted so a collision is always reported the same way
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				child := v[k]
+				name := envName(k)
+				if key != "" {
+					name = key + "_" + name
+				}
+				if err := walk(child, name, jsonPath+"."+k); err != nil {
+					return err
+				}
+			}
+			return nil
+		case []any:
+			for i, child := range v {
+				idx := strconv.Itoa(i)
+				if err := walk(child, key+"_"+idx, jsonPath+"["+idx+"]"); err != nil {
+					return err
+				}
+			}
+			return nil
+		case nil:
+			return nil
+		case string:
+			val = v
+		case json.Number:
+			val = v.String()
+		case bool:
+			val = strconv.FormatBool(v)
+		}
+		if prev, ok := values[key]; ok {
+			return fmt.Errorf("%s and %s both map to %s", prev.jsonPath, jsonPath, key)
+		}
+		values[key] = fileValue{value: val, path: path, jsonPath: jsonPath}
+		return nil
+	}
+	return values, walk(root, "", "$")
+}
+
+// envName : a JSON key as part of an env name, maxConns and max-conns -> MAX_CONNS
+func envName(key string) string {
+	parts := strings.FieldsFunc(key, func(r rune) bool {
+		return r == '-' || r == '_' || r == '.' || r == ' '
+	})
+	for i, part := range parts {
+		parts[i] = screamingSnake(part)
+	}
+	return strings.Join(parts, "_")
+}
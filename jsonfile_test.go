@@ -0,0 +1,72 @@
+package env_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/taybart/env"
+	"github.com/taybart/env/envtest"
+)
+
+func TestLoadJSONFile(t *testing.T) {
+	is := is.New(t)
+	e := envtest.New(t, nil)
+	path := filepath.Join(t.TempDir(), "config.json")
+	is.NoErr(os.WriteFile(path, []byte(`{
+  "db": {"host": "db.internal", "maxConns": 10, "ssl-mode": "require"},
+  "debug": true,
+  "replicas": [{"host": "r0"}, {"host": "r1"}],
+  "ratio": 0.10,
+  "unused": null
+}`), 0o600))
+	e.Setenv("DB_HOST", "override")
+
+	is.NoErr(e.LoadJSONFile(path))
+	is.NoErr(e.Ensure([]string{
+		"DB_HOST", "DB_MAX_CONNS | int", "DB_SSL_MODE", "DEBUG | bool",
+		"REPLICAS_0_HOST", "REPLICAS_1_HOST", "RATIO", "UNUSED?",
+	}))
+	is.Equal(e.Get("DB_HOST"), "override") // env wins over the file
+	is.Equal(e.Int("DB_MAX_CONNS"), 10)
+	is.Equal(e.Get("DB_SSL_MODE"), "require")
+	is.True(e.Bool("DEBUG"))
+	is.Equal(e.Get("REPLICAS_1_HOST"), "r1")
+	is.Equal(e.Get("RATIO"), "0.10") // numbers keep their text
+	is.True(!e.Has("UNUSED"))
+
+	p := e.ProvenanceOf("REPLICAS_0_HOST")
+	is.Equal(p.Kind, env.FromFile)
+	is.Equal(p.Location, path)
+	is.Equal(p.Path, "$.replicas[0].host")
+	is.Equal(e.ProvenanceOf("DB_HOST").Kind, env.FromEnv)
+	is.True(strings.Contains(e.ConfigReport().String(), "[file "+path+" $.db.maxConns]"))
+
+	// reload reads the JSON again
+	is.NoErr(os.WriteFile(path, []byte(`{"db": {"host": "x", "maxConns": 20, "ssl-mode": "off"},
+"debug": false, "replicas": [{"host": "r0"}, {"host": "r1"}], "ratio": 1}`), 0o600))
+	is.NoErr(e.Reload())
+	is.Equal(e.Int("DB_MAX_CONNS"), 20)
+	is.True(!e.Bool("DEBUG"))
+}
+
+func TestLoadJSONFileErrors(t *testing.T) {
+	is := is.New(t)
+	e := envtest.New(t, nil)
+	dir := t.TempDir()
+	write := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		is.NoErr(os.WriteFile(path, []byte(contents), 0o600))
+		return path
+	}
+
+	err := e.LoadJSONFile(write("collide.json", `{"db": {"host": "a"}, "db_host": "b"}`))
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), "$.db.host and $.db_host both map to DB_HOST"))
+
+	is.True(e.LoadJSONFile(write("array.json", `[1, 2]`)) != nil)
+	is.True(e.LoadJSONFile(write("bad.json", `{"a": `)) != nil)
+	is.True(e.LoadJSONFile(filepath.Join(dir, "missing.json")) != nil)
+}
@@ -0,0 +1,123 @@
+package env
+
+import "fmt"
+
+// Scope : a view of the environment where every key is prefixed, so a
+// library can declare HOST and PORT and let the application pick CACHE_HOST
+type Scope struct {
+	env    *Environment
+	prefix string
+}
+
+// WithPrefix : returns a Scope that prepends prefix to every key
+func (e *Environment) WithPrefix(prefix string) Scope {
+	for i := 0; i < len(prefix); i++ {
+		if !isNameByte(prefix[i], i == 0) {
+			panic(fmt.Sprintf("invalid env prefix %q", prefix))
+		}
+	}
+	return Scope{env: e, prefix: prefix}
+}
+
+// WithPrefix : nest another prefix inside this scope
+func (s Scope) WithPrefix(prefix string) Scope {
+	return s.environment().WithPrefix(s.prefix + prefix)
+}
+
+// environment : the zero Scope uses the default environment
+func (s Scope) environment() *Environment {
+	if s.env == nil {
+		return Default()
+	}
+	return s.env
+}
+
+// Prefix : returns the full prefix of the scope
+func (s Scope) Prefix() string {
+	return s.prefix
+}
+
+// Key : returns the fully qualified name of key
+func (s Scope) Key(key string) string {
+	return s.prefix + key
+}
+
+// Add : same as env.Add with every key prefixed
+func (s Scope) Add(keys []string) {
+	err := s.Ensure(keys)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Ensure : same as env.Ensure with every key prefixed
+func (s Scope) Ensure(keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	e := s.environment()
+	if e.IsFrozen() {
+		return ErrFrozen
+	}
+	parsed := make([]Spec, len(keys))
+	for i, key := range keys {
+		spec, err := ParseSpec(key)
+		if err != nil {
+			return err
+		}
+		spec.Name = s.Key(spec.Name)
+		parsed[i] = spec
+	}
+	return e.ensure(parsed)
+}
+
+// Has : see env.Has
+func (s Scope) Has(key string) bool { return s.environment().Has(s.Key(key)) }
+
+// Is : see env.Is
+func (s Scope) Is(key, compare string) bool { return s.environment().Is(s.Key(key), compare) }
+
+// IsSet : see env.IsSet
+func (s Scope) IsSet(key string) bool { return s.environment().IsSet(s.Key(key)) }
+
+// IsDefault : see env.IsDefault
+func (s Scope) IsDefault(key string) bool { return s.environment().IsDefault(s.Key(key)) }
+
+// Get : see env.Get
+func (s Scope) Get(key string) string { return s.environment().Get(s.Key(key)) }
+
+// Decode : see env.Decode
+func (s Scope) Decode(key string, enc ...Encoding) ([]byte, error) {
+	return s.environment().Decode(s.Key(key), enc...)
+}
+
+// DecodeTo : see env.DecodeTo
+func (s Scope) DecodeTo(key string, out any, enc ...Encoding) error {
+	return s.environment().DecodeTo(s.Key(key), out, enc...)
+}
+
+// Int : see env.Int
+func (s Scope) Int(key string) int { return s.environment().Int(s.Key(key)) }
+
+// Bool : see env.Bool
+func (s Scope) Bool(key string) bool { return s.environment().Bool(s.Key(key)) }
+
+// JSON : see env.JSON
+func (s Scope) JSON(key string, input any) error {
+	return s.environment().JSON(s.Key(key), input)
+}
+
+// JSONWith : see env.JSONWith
+func (s Scope) JSONWith(key string, input any, opts JSONOptions) error {
+	return s.environment().JSONWith(s.Key(key), input, opts)
+}
+
+// Prefixed : see env.Prefixed
+func (s Scope) Prefixed(prefix string) map[string]string {
+	return s.environment().Prefixed(s.Key(prefix))
+}
+
+// Indexed : see env.Indexed
+func (s Scope) Indexed(prefix string, out any) error {
+	return s.environment().Indexed(s.Key(prefix), out)
+}
@@ -0,0 +1,32 @@
+package env_test
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/taybart/env"
+	"github.com/taybart/env/envtest"
+)
+
+func TestWithPrefix(t *testing.T) {
+	is := is.New(t)
+	e := envtest.Global(t, nil)
+	e.Setenv("TEST_CACHE_HOST", "cache.local")
+	e.Setenv("TEST_DB_PRIMARY_HOST", "primary.local")
+
+	// the same declarations in two namespaces
+	declare := func(s env.Scope) {
+		s.Add([]string{"HOST", "PORT=6379 | int"})
+	}
+	cache := env.WithPrefix("TEST_CACHE_")
+	db := env.WithPrefix("TEST_DB_").WithPrefix("PRIMARY_")
+	declare(cache)
+	declare(db)
+
+	is.Equal(db.Prefix(), "TEST_DB_PRIMARY_")
+	is.Equal(cache.Get("HOST"), "cache.local")
+	is.Equal(db.Get("HOST"), "primary.local")
+	is.Equal(cache.Int("PORT"), 6379)
+	is.Equal(env.Int("TEST_CACHE_PORT"), 6379)
+	is.True(cache.IsDefault("PORT"))
+}
@@ -0,0 +1,227 @@
+package env
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Prefixed : returns every variable that starts with prefix, keyed by the
+// rest of its name. LABEL_team=core with prefix LABEL_ gives {"team": "core"}
+// An empty prefix would match the whole env and returns nil.
+func (e *Environment) Prefixed(prefix string) map[string]string {
+	if prefix == "" {
+		return nil
+	}
+	found := make(map[string]string)
+	for k, v := range e.environ() {
+		if strings.HasPrefix(k, prefix) && len(k) > len(prefix) {
+			found[k[len(prefix):]] = v
+			e.seen(k)
+		}
+	}
+	return found
+}
+
+/* Indexed : decode numbered variables into a slice of structs. Fields map to
+ * their `env` tag, or the field name in SCREAMING_SNAKE_CASE.
+ *   UPSTREAM_0_HOST=a UPSTREAM_0_PORT=80 UPSTREAM_1_HOST=b
+ *   var ups []struct{ Host string; Port int }
+ *   env.Indexed("UPSTREAM_", &ups)
+ * Indexes must start at zero and have no gaps. Use `env:"NAME,required"` to
+ * require a field in every element, see envTag.
+ */
+func (e *Environment) Indexed(prefix string, out any) error {
+	ptr := reflect.ValueOf(out)
+	if ptr.Kind() != reflect.Pointer || ptr.Elem().Kind() != reflect.Slice ||
+		ptr.Elem().Type().Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("env.Indexed needs a pointer to a slice of structs, got %T", out)
+	}
+	if prefix == "" {
+		return fmt.Errorf("env.Indexed needs a prefix")
+	}
+
+	// group values by index
+	elems := make(map[int]map[string]string)
+	for rest, v := range e.Prefixed(prefix) {
+		idx, field, ok := strings.Cut(rest, "_")
+		if !ok {
+			continue
+		}
+		i, err := strconv.Atoi(idx)
+		if err != nil || i < 0 {
+			continue
+		}
+		if elems[i] == nil {
+			elems[i] = make(map[string]string)
+		}
+		elems[i][field] = v
+	}
+	indexes := make([]int, 0, len(elems))
+	for i := range elems {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	for want, i := range indexes {
+		if i != want {
+			return fmt.Errorf("missing %s%d_*, indexes must start at 0 with no gaps", prefix, want)
+		}
+	}
+
+	slice := reflect.MakeSlice(ptr.Elem().Type(), len(indexes), len(indexes))
+	for _, i := range indexes {
+		if err := setFields(slice.Index(i), elems[i], fmt.Sprintf("%s%d_", prefix, i)); err != nil {
+			return err
+		}
+	}
+	ptr.Elem().Set(slice)
+	return nil
+}
+
+// setFields : fill the fields of struct sv from values, prefix is only used
+// in errors
+func setFields(sv reflect.Value, values map[string]string, prefix string) error {
+	st := sv.Type()
+	for f := 0; f < st.NumField(); f++ {
+		field := st.Field(f)
+		if !field.IsExported() {
+			continue
+		}
+		name, required := envTag(field)
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = screamingSnake(field.Name)
+		}
+		val, found := values[name]
+		if !found {
+			if required {
+				return fmt.Errorf("missing required %s%s", prefix, name)
+			}
+			continue
+		}
+		if err := setValue(sv.Field(f), val); err != nil {
+			return fmt.Errorf("%s%s: %w", prefix, name, err)
+		}
+	}
+	return nil
+}
+
+// envTag : the parts of an `env:"NAME,required"` struct tag, the same
+// grammar everywhere. NAME renames the variable for Indexed and is left empty
+// where the name comes from elsewhere, ex. `json:"host" env:",required"`.
+func envTag(field reflect.StructField) (name string, required bool) {
+	name, opts, _ := strings.Cut(field.Tag.Get("env"), ",")
+	for _, opt := range strings.Split(opts, ",") {
+		required = required || opt == "required"
+	}
+	return name, required
+}
+
+// setValue : parse val into the kind of v
+func setValue(v reflect.Value, val string) error {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(val, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(val, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(val, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
+
+// screamingSnake : MaxConns -> MAX_CONNS
+func screamingSnake(name string) string {
+	var sb strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) &&
+			(unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			sb.WriteByte('_')
+		}
+		sb.WriteRune(unicode.ToUpper(r))
+	}
+	return sb.String()
+}
+
+// environ : every variable visible to the getters, including the frozen
+// snapshot and in memory defaults
+func (e *Environment) environ() map[string]string {
+	if frozen := e.frozen.Load(); frozen != nil {
+		all := make(map[string]string, len(frozen.values))
+		for k, v := range frozen.values {
+			all[k] = v
+		}
+		return all
+	}
+	return e.liveEnviron()
+}
+
+// liveEnviron : every variable from Set, flags, the source, files and defaults
+func (e *Environment) liveEnviron() map[string]string {
+	all := make(map[string]string)
+	for k, v := range e.reg().defaults {
+		all[k] = v
+	}
+	for k, fv := range e.fileLayer() {
+		all[k] = fv.value
+	}
+	for k, v := range e.aboveFiles() {
+		all[k] = v
+	}
+	return all
+}
+
+// aboveFiles : every variable from Set, flags and the source
+func (e *Environment) aboveFiles() map[string]string {
+	r := e.reg()
+	all := make(map[string]string)
+	for k, v := range e.source.Environ() {
+		if !e.writtenDefault(r, k, v) {
+			all[k] = v
+		}
+	}
+	for k, f := range r.flags {
+		all[k] = f.value
+	}
+	for k, v := range r.runtime {
+		all[k] = v
+	}
+	return all
+}
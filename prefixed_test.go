@@ -0,0 +1,80 @@
+package env_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/taybart/env"
+	"github.com/taybart/env/envtest"
+)
+
+func TestPrefixed(t *testing.T) {
+	is := is.New(t)
+	e := envtest.Global(t, nil)
+	e.Setenv("TEST_LABEL_team", "core")
+	e.Setenv("TEST_LABEL_tier", "1")
+
+	labels := env.Prefixed("TEST_LABEL_")
+	is.Equal(labels, map[string]string{"team": "core", "tier": "1"})
+	is.True(contains(env.UsageReport().Undeclared, "TEST_LABEL_team") == false)
+
+	// an empty prefix matches nothing and marks nothing as seen
+	e.Setenv("TEST_LABEL_UNSEEN", "x")
+	is.Equal(env.Prefixed(""), nil)
+	is.Equal(env.UsageReport().Reads["TEST_LABEL_UNSEEN"], 0)
+}
+
+func TestIndexed(t *testing.T) {
+	is := is.New(t)
+	e := envtest.Global(t, nil)
+	e.Setenv("TEST_UPSTREAM_0_HOST", "a.local")
+	e.Setenv("TEST_UPSTREAM_0_PORT", "80")
+	e.Setenv("TEST_UPSTREAM_0_DIAL_TIMEOUT", "2s")
+	e.Setenv("TEST_UPSTREAM_1_HOST", "b.local")
+	e.Setenv("TEST_UPSTREAM_1_WEIGHT", "3")
+
+	type upstream struct {
+		Host        string `env:"HOST,required"`
+		Port        int
+		DialTimeout time.Duration
+		Weight      uint8
+	}
+	var ups []upstream
+	is.NoErr(env.Indexed("TEST_UPSTREAM_", &ups))
+	is.Equal(ups, []upstream{
+		{Host: "a.local", Port: 80, DialTimeout: 2 * time.Second},
+		{Host: "b.local", Weight: 3},
+	})
+
+	// gaps are an error
+	e.Setenv("TEST_UPSTREAM_3_HOST", "d.local")
+	is.True(env.Indexed("TEST_UPSTREAM_", &ups) != nil)
+
+	// so are missing required fields
+	e.Setenv("TEST_UPSTREAM_2_PORT", "80")
+	is.True(env.Indexed("TEST_UPSTREAM_", &ups) != nil)
+
+	is.True(env.Indexed("", &ups) != nil)
+}
+
+func TestEnvTag(t *testing.T) {
+	is := is.New(t)
+	e := envtest.New(t, map[string]string{
+		"TEST_TAG_0_PORT": "80",
+		"TEST_TAG_JSON":   `{"port": 80}`,
+	})
+
+	// the name may be left out, it still requires the field
+	var ups []struct {
+		Host string `json:"host" env:",required"`
+		Port int
+	}
+	err := e.Indexed("TEST_TAG_", &ups)
+	is.True(err != nil)
+	is.Equal(err.Error(), "missing required TEST_TAG_0_HOST")
+	err = e.JSONWith("TEST_TAG_JSON", &struct {
+		Host string `json:"host" env:",required"`
+	}{}, env.JSONOptions{RequireFields: true})
+	is.True(err != nil)
+}
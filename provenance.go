@@ -0,0 +1,61 @@
+package env
+
+// Where a value came from, in order of precedence
+const (
+	// FromRuntime values were changed with Set
+	FromRuntime = "runtime"
+	FromFlag    = "flag"
+	FromEnv     = "env"
+	FromFile    = "file"
+	FromDefault = "default"
+	// FromComputed values come from a default function, see AddComputed
+	FromComputed = "computed"
+	FromUnset    = "unset"
+)
+
+// Provenance : where the value of a key came from
+type Provenance struct {
+	// Kind is one of the From* constants
+	Kind string
+	// Location is extra detail for the kind, ex. the flag name or file path
+	Location string
+	// Path of the value inside a JSON config file, ex. $.db.host
+	Path string
+}
+
+func (p Provenance) String() string {
+	s := p.Kind
+	if p.Location != "" {
+		s += " " + p.Location
+	}
+	if p.Path != "" {
+		s += " " + p.Path
+	}
+	return s
+}
+
+// ProvenanceOf : returns where the current value of key comes from
+func (e *Environment) ProvenanceOf(key string) Provenance {
+	r := e.reg()
+	if _, ok := r.runtime[key]; ok {
+		return Provenance{Kind: FromRuntime}
+	}
+	if f, ok := r.flags[key]; ok {
+		return Provenance{Kind: FromFlag, Location: "-" + f.name}
+	}
+	// defaults written to the process env look like env values
+	if val, found := e.source.Lookup(key); found && !e.writtenDefault(r, key, val) {
+		return Provenance{Kind: FromEnv}
+	}
+	_, hasDefault := r.defaults[key]
+	if fv, ok := e.fileLayer()[key]; ok {
+		return Provenance{Kind: FromFile, Location: fv.path, Path: fv.jsonPath}
+	}
+	if name, ok := r.computed[key]; ok && hasDefault {
+		return Provenance{Kind: FromComputed, Location: name}
+	}
+	if hasDefault {
+		return Provenance{Kind: FromDefault}
+	}
+	return Provenance{Kind: FromUnset}
+}
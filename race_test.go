@@ -0,0 +1,119 @@
+package env_test
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/taybart/env"
+	"github.com/taybart/env/envtest"
+)
+
+// TestConcurrentRegistry : declare from some goroutines while others read,
+// run with -race. Goroutines report errors on a channel, only the test
+// goroutine may fail the test.
+func TestConcurrentRegistry(t *testing.T) {
+	is := is.New(t)
+	e := envtest.New(t, map[string]string{"HOT": "1"})
+	e.DefaultsInProcess(false)
+	e.Add([]string{"HOT | int"})
+
+	errs := make(chan error, 100)
+	check := func(err error) {
+		if err != nil {
+			select {
+			case errs <- err:
+			default: // the first ones are enough
+			}
+		}
+	}
+	var readers sync.WaitGroup
+	stop := make(chan struct{})
+	for r := 0; r < 4; r++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if e.Int("HOT") != 1 {
+					check(fmt.Errorf("HOT is %d", e.Int("HOT")))
+				}
+				e.Has("KEY_0_0")
+				e.ProvenanceOf("KEY_1_1")
+				e.ConfigReport()
+				e.Environ(env.EnvironOptions{})
+				e.UsageReport()
+			}
+		}()
+	}
+
+	var writers sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		writers.Add(1)
+		go func(w int) {
+			defer writers.Done()
+			for i := 0; i < 50; i++ {
+				key := fmt.Sprintf("KEY_%d_%d", w, i)
+				check(e.Ensure([]string{key + "=" + key}))
+				e.IntVar(fmt.Sprintf("VAR_%d_%d", w, i)).Default(i)
+			}
+		}(w)
+	}
+	writers.Add(1)
+	go func() {
+		defer writers.Done()
+		// validators are registered while specs are checked
+		for i := 0; i < 50; i++ {
+			env.RegisterValidator(fmt.Sprintf("race_%d", i), func(string, []string) error { return nil })
+			check(e.Ensure([]string{fmt.Sprintf("CHECKED_%d=1 | race_%d", i, i)}))
+		}
+	}()
+	writers.Add(1)
+	go func() {
+		defer writers.Done()
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		check(e.BindFlags(fs, []string{"HOT | int"}))
+		check(fs.Parse([]string{"-hot=1"}))
+	}()
+	writers.Wait()
+
+	// declaring while frozen is an error, so only readers run alongside Freeze
+	for i := 0; i < 20; i++ {
+		check(e.Freeze())
+		e.Unfreeze()
+	}
+	close(stop)
+	readers.Wait()
+	close(errs)
+	for err := range errs {
+		is.NoErr(err)
+	}
+
+	is.NoErr(e.Validate())
+	spec, ok := e.Spec("KEY_3_49")
+	is.True(ok)
+	is.Equal(spec.Default, "KEY_3_49")
+	is.Equal(e.Get("KEY_3_49"), "KEY_3_49")
+	is.Equal(e.Int("VAR_2_7"), 7)
+	is.True(e.UsageReport().Reads["HOT"] > 0)
+}
+
+// BenchmarkGet : the read path takes no locks
+func BenchmarkGet(b *testing.B) {
+	e := envtest.New(b, map[string]string{"HOT": "42"})
+	e.Add([]string{"HOT | int"})
+	e.Freeze()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			e.Int("HOT")
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package env
+
+// registry : everything declared on an Environment. A published registry is
+// never modified, writers copy it and swap the pointer so getters can read
+// it without locking.
+type registry struct {
+	// declared specs by name
+	specs    map[string]Spec
+	defaults map[string]string
+	// name of the function that computed a default, by key
+	computed map[string]string
+	// values set on the command line by key
+	flags map[string]flagValue
+	// values changed with Set by key
+	runtime map[string]string
+	// write defaults to the source so child processes inherit them
+	defaultsInProcess bool
+}
+
+// flagValue : a value set on the command line
+type flagValue struct {
+	name  string
+	value string
+}
+
+func newRegistry() *registry {
+	return &registry{
+		specs:             make(map[string]Spec),
+		defaults:          make(map[string]string),
+		computed:          make(map[string]string),
+		flags:             make(map[string]flagValue),
+		runtime:           make(map[string]string),
+		defaultsInProcess: true,
+	}
+}
+
+// clone : a copy of r that can be modified
+func (r *registry) clone() *registry {
+	c := &registry{
+		specs:             make(map[string]Spec, len(r.specs)),
+		defaults:          make(map[string]string, len(r.defaults)),
+		computed:          make(map[string]string, len(r.computed)),
+		flags:             make(map[string]flagValue, len(r.flags)),
+		runtime:           make(map[string]string, len(r.runtime)),
+		defaultsInProcess: r.defaultsInProcess,
+	}
+	for k, v := range r.specs {
+		c.specs[k] = v
+	}
+	for k, v := range r.defaults {
+		c.defaults[k] = v
+	}
+	for k, v := range r.computed {
+		c.computed[k] = v
+	}
+	for k, v := range r.flags {
+		c.flags[k] = v
+	}
+	for k, v := range r.runtime {
+		c.runtime[k] = v
+	}
+	return c
+}
+
+// reg : the current registry, never modify it
+func (e *Environment) reg() *registry {
+	return e.registry.Load()
+}
+
+// update : modify a copy of the registry and publish it, writers are
+// serialized so no update is lost
+func (e *Environment) update(fn func(r *registry)) {
+	e.regMu.Lock()
+	defer e.regMu.Unlock()
+	r := e.reg().clone()
+	fn(r)
+	e.registry.Store(r)
+}
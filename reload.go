@@ -0,0 +1,207 @@
+package env
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/taybart/log"
+)
+
+// Subscribe : call fn with the old and new value whenever a reload changes
+// key. Returns a function that removes the subscription.
+func (e *Environment) Subscribe(key string, fn func(old, new string)) (unsubscribe func()) {
+	e.subsMu.Lock()
+	defer e.subsMu.Unlock()
+	id := e.nextSub
+	e.nextSub++
+	if e.subscribers[key] == nil {
+		e.subscribers[key] = make(map[int]func(old, new string))
+	}
+	e.subscribers[key][id] = fn
+	return func() {
+		e.subsMu.Lock()
+		defer e.subsMu.Unlock()
+		delete(e.subscribers[key], id)
+	}
+}
+
+// notify : tell subscribers of key that it changed
+func (e *Environment) notify(key, old, new string) {
+	e.subsMu.Lock()
+	fns := make([]func(old, new string), 0, len(e.subscribers[key]))
+	for _, fn := range e.subscribers[key] {
+		fns = append(fns, fn)
+	}
+	e.subsMu.Unlock()
+	for _, fn := range fns {
+		fn(old, new)
+	}
+}
+
+// LastReload : when the env files were last reloaded, zero if never
+func (e *Environment) LastReload() time.Time {
+	e.reloadMu.Lock()
+	defer e.reloadMu.Unlock()
+	return e.lastReload
+}
+
+// Reload : re-read every loaded env file and validate the declared env
+// against it. The new values are only swapped in if they are valid, then
+// subscribers are notified of every key that changed.
+func (e *Environment) Reload() error {
+	e.reloadMu.Lock()
+	defer e.reloadMu.Unlock()
+
+	// files loaded while reloading must not be dropped by the swap
+	before, err := func() (map[string]string, error) {
+		e.filesMu.Lock()
+		defer e.filesMu.Unlock()
+		layer, err := e.readFiles(e.loadedFiles)
+		if err != nil {
+			return nil, fmt.Errorf("reload: %w", err)
+		}
+		err = e.validateSpecs(func(key string) (string, bool) {
+			return e.resolveIn(key, layer)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("reload rejected: %w", err)
+		}
+		before := e.declaredValues()
+		e.fileValues.Store(&layer)
+		// only the files change, drift in the process env stays visible
+		if frozen := e.frozen.Load(); frozen != nil {
+			e.frozen.Store(newSnapshot(frozen.defaults, layer, frozen.above))
+		}
+		return before, nil
+	}()
+	if err != nil {
+		return err
+	}
+	after := e.declaredValues()
+	e.lastReload = time.Now()
+
+	keys := make([]string, 0, len(after))
+	for k := range after {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if before[k] != after[k] {
+			e.notify(k, before[k], after[k])
+		}
+	}
+	return nil
+}
+
+// Watch : reload when a loaded file changes, checking every interval, or when
+// the process gets SIGHUP. An interval <= 0 only reloads on SIGHUP. Failed
+// reloads are logged and the old values kept.
+func (e *Environment) Watch(interval time.Duration) (stop func()) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	done := make(chan struct{})
+	// a nil channel never fires, so without a ticker only SIGHUP reloads
+	var ticker *time.Ticker
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker = time.NewTicker(interval)
+		tick = ticker.C
+	}
+
+	mtimes := e.fileMtimes()
+	reload := func() {
+		if err := e.Reload(); err != nil {
+			log.Errorf("%v\n", err)
+		}
+	}
+	go func() {
+		for {
+			select {
+			case <-hup:
+				reload()
+				mtimes = e.fileMtimes()
+			case <-tick:
+				current := e.fileMtimes()
+				for path, t := range current {
+					if !t.Equal(mtimes[path]) {
+						reload()
+						break
+					}
+				}
+				mtimes = current
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(hup)
+		if ticker != nil {
+			ticker.Stop()
+		}
+		close(done)
+	}
+}
+
+// fileMtimes : modification times of the loaded files
+func (e *Environment) fileMtimes() map[string]time.Time {
+	e.filesMu.Lock()
+	paths := append([]string(nil), e.loadedFiles...)
+	e.filesMu.Unlock()
+	mtimes := make(map[string]time.Time, len(paths))
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			mtimes[path] = info.ModTime()
+		}
+	}
+	return mtimes
+}
+
+// declaredValues : the current value of every declared key
+func (e *Environment) declaredValues() map[string]string {
+	specs := e.reg().specs
+	values := make(map[string]string, len(specs))
+	for k := range specs {
+		val, found, ok := e.frozenLookup(k)
+		if !ok {
+			val, found = e.resolve(k)
+		}
+		if found {
+			values[k] = val
+		}
+	}
+	return values
+}
+
+// validateSpecs : check every declared spec against the values from fn
+func (e *Environment) validateSpecs(fn func(key string) (string, bool)) error {
+	missing := []string{}
+	invalid := []string{}
+	for name, spec := range e.reg().specs {
+		val, found := fn(name)
+		switch {
+		case !found && !spec.Optional:
+			missing = append(missing, name)
+		case found && val == "" && !(spec.Optional || spec.AllowEmpty || spec.HasDefault):
+			missing = append(missing, name)
+		case found:
+			if err := validate(spec, val); err != nil {
+				invalid = append(invalid, err.Error())
+			}
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(invalid)
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required environment variables: %v", missing)
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("invalid environment variables: %s", strings.Join(invalid, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package env_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/taybart/env"
+)
+
+func TestReload(t *testing.T) {
+	is := is.New(t)
+	e := env.New(env.NewMapSource(nil))
+
+	path := filepath.Join(t.TempDir(), ".env")
+	write := func(contents string) {
+		is.NoErr(os.WriteFile(path, []byte(contents), 0o600))
+	}
+	write("TEST_RELOAD_LEVEL=info\nTEST_RELOAD_PORT=80\n")
+	is.NoErr(e.LoadFile(path))
+	e.Add([]string{"TEST_RELOAD_LEVEL | oneof(debug,info)", "TEST_RELOAD_PORT | int"})
+	is.NoErr(e.Freeze())
+
+	changes := make(chan [2]string, 1)
+	unsubscribe := e.Subscribe("TEST_RELOAD_LEVEL", func(old, new string) {
+		changes <- [2]string{old, new}
+	})
+	defer unsubscribe()
+
+	// invalid files are rejected and the old values kept
+	write("TEST_RELOAD_LEVEL=loud\nTEST_RELOAD_PORT=80\n")
+	is.True(e.Reload() != nil)
+	is.Equal(e.Get("TEST_RELOAD_LEVEL"), "info")
+
+	write("TEST_RELOAD_LEVEL=debug\nTEST_RELOAD_PORT=80\n")
+	is.NoErr(e.Reload())
+	is.Equal(e.Get("TEST_RELOAD_LEVEL"), "debug")
+	is.Equal(<-changes, [2]string{"info", "debug"})
+	is.True(!e.LastReload().IsZero())
+
+	// the watcher picks up file changes
+	stop := e.Watch(10 * time.Millisecond)
+	defer stop()
+	time.Sleep(20 * time.Millisecond)
+	write("TEST_RELOAD_LEVEL=info\nTEST_RELOAD_PORT=80\n")
+	// make sure the mtime moves even on coarse filesystems
+	is.NoErr(os.Chtimes(path, time.Now().Add(time.Second), time.Now().Add(time.Second)))
+	select {
+	case change := <-changes:
+		is.Equal(change, [2]string{"debug", "info"})
+	case <-time.After(2 * time.Second):
+		t.Fatal("watcher did not reload")
+	}
+}
+
+func TestReloadWhileLoading(t *testing.T) {
+	is := is.New(t)
+	e := env.New(env.NewMapSource(nil))
+	dir := t.TempDir()
+
+	stop, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				_ = e.Reload()
+			}
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		path := filepath.Join(dir, fmt.Sprintf(".env.%d", i))
+		is.NoErr(os.WriteFile(path, []byte(fmt.Sprintf("TEST_RELOAD_LOAD_%d=x\n", i)), 0o600))
+		is.NoErr(e.LoadFile(path))
+	}
+	close(stop)
+	<-done
+	// no load is dropped by a concurrent reload
+	for i := 0; i < 50; i++ {
+		is.True(e.Has(fmt.Sprintf("TEST_RELOAD_LOAD_%d", i)))
+	}
+}
+
+func TestReloadKeepsDrift(t *testing.T) {
+	is := is.New(t)
+	src := env.NewMapSource(map[string]string{"TEST_RELOAD_HOST": "a"})
+	e := env.New(src)
+
+	path := filepath.Join(t.TempDir(), ".env")
+	is.NoErr(os.WriteFile(path, []byte("TEST_RELOAD_LEVEL=info\n"), 0o600))
+	is.NoErr(e.LoadFile(path))
+	e.Add([]string{"TEST_RELOAD_HOST", "TEST_RELOAD_LEVEL"})
+	is.NoErr(e.Freeze())
+
+	is.NoErr(src.Set("TEST_RELOAD_HOST", "b"))
+	is.NoErr(os.WriteFile(path, []byte("TEST_RELOAD_LEVEL=debug\n"), 0o600))
+	is.NoErr(e.Reload())
+
+	// the file change is picked up but the process env drift is not
+	is.Equal(e.Get("TEST_RELOAD_LEVEL"), "debug")
+	is.Equal(e.Get("TEST_RELOAD_HOST"), "a")
+	is.Equal(e.Drifted(), []env.Drift{{Key: "TEST_RELOAD_HOST", Frozen: "a", Current: "b"}})
+}
+
+func TestWatchSighupOnly(t *testing.T) {
+	is := is.New(t)
+	e := env.New(env.NewMapSource(nil))
+
+	path := filepath.Join(t.TempDir(), ".env")
+	is.NoErr(os.WriteFile(path, []byte("TEST_RELOAD_HUP=a\n"), 0o600))
+	is.NoErr(e.LoadFile(path))
+	e.Add([]string{"TEST_RELOAD_HUP"})
+
+	changes := make(chan string, 1)
+	unsubscribe := e.Subscribe("TEST_RELOAD_HUP", func(_, new string) { changes <- new })
+	defer unsubscribe()
+
+	// no ticker, so only the signal reloads
+	stop := e.Watch(0)
+	defer stop()
+	is.NoErr(os.WriteFile(path, []byte("TEST_RELOAD_HUP=b\n"), 0o600))
+	self, err := os.FindProcess(os.Getpid())
+	is.NoErr(err)
+	is.NoErr(self.Signal(syscall.SIGHUP))
+	select {
+	case v := <-changes:
+		is.Equal(v, "b")
+	case <-time.After(2 * time.Second):
+		t.Fatal("SIGHUP did not reload")
+	}
+}
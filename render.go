@@ -0,0 +1,95 @@
+package env
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+/* Render : execute a text/template against the declared env and write the
+ * result to w, ex. to generate config files at container start
+ *   listen {{int "PORT"}};
+ *   server_name {{required "HOST"}};
+ *   {{if bool "TLS"}}ssl_certificate {{env "CERT_PATH"}};{{end}}
+ *   worker_processes {{default "WORKERS" "auto"}};
+ * The functions are env, int, bool, duration, required and default, which
+ * takes the key then the value used when it is unset or empty. Every key
+ * must be declared and valid, nothing is written to w if rendering fails.
+ */
+func (e *Environment) Render(text string, w io.Writer) error {
+	return e.WithPrefix("").Render(text, w)
+}
+
+// Render : same as env.Render with every key prefixed
+func (s Scope) Render(text string, w io.Writer) error {
+	tmpl, err := template.New("env").Funcs(s.templateFuncs()).Parse(text)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		return err
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// templateFuncs : the functions Render templates can call
+func (s Scope) templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"env": func(key string) (string, error) {
+			val, _, err := s.renderValue(key)
+			return val, err
+		},
+		"int": func(key string) (int, error) {
+			val, found, err := s.renderValue(key)
+			if err != nil || !found {
+				return 0, err
+			}
+			return strconv.Atoi(val)
+		},
+		"bool": func(key string) (bool, error) {
+			val, _, err := s.renderValue(key)
+			return val == "true", err
+		},
+		"duration": func(key string) (time.Duration, error) {
+			val, found, err := s.renderValue(key)
+			if err != nil || !found {
+				return 0, err
+			}
+			return time.ParseDuration(val)
+		},
+		"required": func(key string) (string, error) {
+			val, found, err := s.renderValue(key)
+			if err == nil && (!found || val == "") {
+				err = fmt.Errorf("%s is required", s.Key(key))
+			}
+			return val, err
+		},
+		"default": func(key, fallback string) (string, error) {
+			val, found, err := s.renderValue(key)
+			if err == nil && (!found || val == "") {
+				val = fallback
+			}
+			return val, err
+		},
+	}
+}
+
+// renderValue : the value of a declared key, checked against its spec
+func (s Scope) renderValue(key string) (string, bool, error) {
+	e := s.environment()
+	key = s.Key(key)
+	spec, ok := e.Spec(key)
+	if !ok {
+		return "", false, fmt.Errorf("%s is not declared", key)
+	}
+	val, found := e.lookup(key)
+	if err := checkValue(spec, val, found); err != nil {
+		return "", false, err
+	}
+	return val, found, nil
+}
@@ -0,0 +1,57 @@
+package env_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/taybart/env/envtest"
+)
+
+func TestRender(t *testing.T) {
+	is := is.New(t)
+	e := envtest.New(t, map[string]string{
+		"HOST":      "example.com",
+		"TLS":       "true",
+		"CERT_PATH": "/etc/cert.pem",
+		"DB_PORT":   "5432",
+	})
+	e.DefaultsInProcess(false)
+	e.Add([]string{"HOST", "PORT=8080 | int", "TLS | bool", "CERT_PATH", "WORKERS?", "GRACE=30s | duration"})
+	e.WithPrefix("DB_").Add([]string{"PORT | int"})
+
+	var sb strings.Builder
+	is.NoErr(e.Render(`listen {{int "PORT"}};
+server_name {{required "HOST"}};
+{{if bool "TLS"}}ssl_certificate {{env "CERT_PATH"}};{{end}}
+worker_processes {{default "WORKERS" "auto"}};
+grace {{(duration "GRACE").Seconds}}s
+`, &sb))
+	is.Equal(sb.String(), `listen 8080;
+server_name example.com;
+ssl_certificate /etc/cert.pem;
+worker_processes auto;
+grace 30s
+`)
+
+	sb.Reset()
+	is.NoErr(e.WithPrefix("DB_").Render(`port={{int "PORT"}}`, &sb))
+	is.Equal(sb.String(), "port=5432")
+
+	fails := map[string]string{
+		`{{env "UNDECLARED"}}`:   "UNDECLARED is not declared",
+		`{{required "WORKERS"}}`: "WORKERS is required",
+		`{{env "HOST"`:           "unclosed action",
+	}
+	for text, want := range fails {
+		sb.Reset()
+		err := e.Render("partial "+text, &sb)
+		is.True(err != nil)
+		is.True(strings.Contains(err.Error(), want))
+		is.Equal(sb.Len(), 0) // nothing written on failure
+	}
+
+	// values are validated against their declaration
+	e.Setenv("PORT", "http")
+	is.True(e.Render(`{{int "PORT"}}`, &sb) != nil)
+}
@@ -0,0 +1,109 @@
+package env
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Redacted replaces the value of secret keys wherever env prints them
+const Redacted = "<redacted>"
+
+// ReportVar : the resolved state of a declared key
+type ReportVar struct {
+	// Spec is the declaration, with the default redacted for secrets
+	Spec       Spec
+	Value      string
+	Set        bool
+	Provenance Provenance
+	// Err is set when the value is missing or fails validation
+	Err error
+}
+
+// Report : every declared key with its value and where it came from
+type Report struct {
+	Vars     []ReportVar
+	Warnings []string
+}
+
+// ConfigReport : describe the current configuration, secret values are redacted
+func (e *Environment) ConfigReport() Report {
+	r := Report{}
+	specs := e.reg().specs
+	names := make([]string, 0, len(specs))
+	for k := range specs {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		spec := specs[k]
+		val, found, ok := e.frozenLookup(k)
+		if !ok {
+			val, found = e.resolve(k)
+		}
+		err := checkValue(spec, val, found)
+		if spec.Secret && found {
+			val = Redacted
+		}
+		if spec.Secret && spec.HasDefault {
+			spec.Default = Redacted
+		}
+		r.Vars = append(r.Vars, ReportVar{
+			Spec:       spec,
+			Value:      val,
+			Set:        found,
+			Provenance: e.ProvenanceOf(k),
+			Err:        err,
+		})
+	}
+	for _, k := range e.IgnoredAmbient() {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("%s is set in the process env but ignored by the hermetic environment", k))
+	}
+	for _, k := range e.undeclaredFileKeys() {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("%s is set in %s but never declared", k, e.fileLayer()[k].path))
+	}
+	return r
+}
+
+// checkValue : the problem with the value of spec, validation errors of
+// secrets leave the value out
+func checkValue(spec Spec, val string, found bool) error {
+	switch {
+	case !found && !spec.Optional:
+		return fmt.Errorf("%s is required", spec.Name)
+	case found && val == "" && !(spec.Optional || spec.AllowEmpty || spec.HasDefault):
+		return fmt.Errorf("%s is required and cannot be empty", spec.Name)
+	case found:
+		return validate(spec, val)
+	}
+	return nil
+}
+
+// validate : spec.Validate, leaving the value of secrets out of the error
+func validate(spec Spec, val string) error {
+	if err := spec.Validate(val); err != nil {
+		if spec.Secret {
+			return fmt.Errorf("%s failed validation", spec.Name)
+		}
+		return err
+	}
+	return nil
+}
+
+func (r Report) String() string {
+	var sb strings.Builder
+	for _, v := range r.Vars {
+		val := fmt.Sprintf("%q", v.Value)
+		if !v.Set {
+			val = "(unset)"
+		}
+		fmt.Fprintf(&sb, "%s=%s [%s]\n", v.Spec.Name, val, v.Provenance)
+		if v.Err != nil {
+			fmt.Fprintf(&sb, "# error: %s\n", v.Err)
+		}
+	}
+	for _, w := range r.Warnings {
+		fmt.Fprintf(&sb, "# warning: %s\n", w)
+	}
+	return sb.String()
+}
@@ -3,6 +3,7 @@ package scan
 import (
 	"fmt"
 	"slices"
+	"sort"
 
 	"github.com/taybart/env"
 )
@@ -11,10 +12,18 @@ type EnvVar struct {
 	Value      string
 	Optional   bool
 	HasDefault bool
+	// AllowEmpty is set for required values that may be empty (NAME*)
+	AllowEmpty  bool
+	Secret      bool
+	Description string
+	// Computed is the name of the function computing the default
+	Computed string
 }
 type Env struct {
 	Values map[string]EnvVar
-	v      *visitor
+	// Prefixes read as a group with env.Prefixed or env.Indexed
+	Prefixes []string
+	v        *visitor
 }
 
 func NewEnv() Env {
@@ -28,16 +37,51 @@ func (e Env) Equal(cmp Env) bool {
 		fmt.Println("env lengths not equal")
 		return false
 	}
+	if !slices.Equal(e.Prefixes, cmp.Prefixes) {
+		fmt.Println("prefixes not equal")
+		return false
+	}
 	for k, v := range e.Values {
 		if v.Value != cmp.Values[k].Value ||
 			v.Optional != cmp.Values[k].Optional ||
-			v.HasDefault != cmp.Values[k].HasDefault {
+			v.HasDefault != cmp.Values[k].HasDefault ||
+			v.AllowEmpty != cmp.Values[k].AllowEmpty ||
+			v.Secret != cmp.Values[k].Secret ||
+			v.Description != cmp.Values[k].Description ||
+			v.Computed != cmp.Values[k].Computed {
 			fmt.Println(k, "not equal")
 			return false
 		}
 	}
 	return true
 }
+
+// Check: Compare the values of an env file with the declared env, returns
+// the keys that are missing and the ones that fall back to a default or a
+// computed value
+func (e Env) Check(values map[string]string) (missing, usingDefault []string) {
+	missing, usingDefault = []string{}, []string{}
+	for k, v := range e.Values {
+		val, ok := values[k]
+		switch {
+		case v.Optional:
+			continue
+		case !ok && v.Computed != "":
+			usingDefault = append(usingDefault, fmt.Sprintf("%s computed by %s", k, v.Computed))
+		case !ok && v.HasDefault:
+			usingDefault = append(usingDefault, fmt.Sprintf("%s=%s", k, v.Value))
+		case !ok:
+			missing = append(missing, k)
+		case val == "" && !v.AllowEmpty && !v.HasDefault:
+			// required values must not be empty
+			missing = append(missing, k)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(usingDefault)
+	return missing, usingDefault
+}
+
 func (e Env) ToFile() string {
 	output := ""
 
@@ -54,11 +98,20 @@ func (e Env) ToFile() string {
 		if entry.Optional {
 			val = "value is marked as optional"
 		}
+		if entry.Computed != "" {
+			val = "computed by " + entry.Computed
+		}
+		if entry.Description != "" {
+			output += fmt.Sprintf("# %s\n", entry.Description)
+		}
 		output += fmt.Sprintf("%s=\"%s\"", v, val)
 		if i < len(order)-1 {
 			output += "\n"
 		}
 	}
+	for _, p := range e.Prefixes {
+		output += fmt.Sprintf("\n# %s*", p)
+	}
 	return output
 }
 
@@ -68,14 +121,20 @@ func (e Env) EnvByFile() string {
 	}
 	output := ""
 	for ns, en := range e.v.env {
-		optional := env.GetOptional(e.v.env[ns])
 		output += fmt.Sprintf("#%s\n", ns)
 		for _, k := range en {
-			key, val := env.GetDefault(k[1 : len(k)-1])
-			if optional[key] {
+			spec, err := env.ParseSpec(k)
+			if err != nil {
+				continue
+			}
+			val := spec.Default
+			if spec.Optional {
 				val = "Value is marked as optional"
 			}
-			output += fmt.Sprintf("%s=\"%s\"\n", key, val)
+			if fn := e.v.computed[spec.Name]; fn != "" {
+				val = "computed by " + fn
+			}
+			output += fmt.Sprintf("%s=\"%s\"\n", spec.Name, val)
 		}
 		output += "\n"
 	}
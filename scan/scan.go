@@ -10,10 +10,8 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
-	"sort"
 	"strings"
 
-	"github.com/taybart/env"
 	"github.com/taybart/log"
 )
 
@@ -84,28 +82,13 @@ func Scan(config Config) (Env, error) {
 	}
 	if config.Validate != "" {
 		log.Debug("Should Validate", config.Validate)
-		foundEnv, optional := v.EnvToMap()
+		foundEnv := v.Finish()
 
 		envToTest, err := parseEnvFile(config.Validate)
 		if err != nil {
 			return Env{}, err
 		}
-		missing := []string{}
-		usingDefault := []string{}
-		for k, v := range foundEnv {
-			if _, ok := envToTest[k]; !ok {
-				if optional[k] {
-					continue
-				}
-				if v != "" {
-					usingDefault = append(usingDefault, fmt.Sprintf("%s=\"%s\"", k, v))
-					continue
-				}
-				missing = append(missing, k)
-			}
-		}
-		sort.Strings(missing)
-		sort.Strings(usingDefault)
+		missing, usingDefault := foundEnv.Check(envToTest)
 
 		if len(missing) > 0 {
 			m := ""
@@ -117,8 +100,7 @@ func Scan(config Config) (Env, error) {
 
 		if len(usingDefault) > 0 {
 			for _, v := range usingDefault {
-				k, d := env.GetDefault(v)
-				log.Warnf("Using default value for %s=%s\n", k, strings.Trim(d, `"`))
+				log.Warnf("Using default value for %s\n", v)
 			}
 		}
 		return Env{}, nil
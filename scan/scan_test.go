@@ -18,13 +18,45 @@ func TestScan(t *testing.T) {
 	is.True(res.Equal(scan.Env{
 		Values: map[string]scan.EnvVar{
 			// main.go
-			"ENV":    {},
-			"PORT":   {Value: "6969", HasDefault: true},
-			"SECURE": {Optional: true},
+			"ENV":      {},
+			"PORT":     {Value: "6969", HasDefault: true, Description: "port to listen on"},
+			"SECURE":   {Optional: true},
+			"PREFIX":   {HasDefault: true},
+			"EMPTY_OK": {AllowEmpty: true},
+			"HOSTNAME": {Computed: "os.Hostname"},
+			// cache.go
+			"CACHE_HOST":      {},
+			"CACHE_PORT":      {Value: "6379", HasDefault: true},
+			"CACHE_POOL_SIZE": {Computed: "poolSize"},
+			"DB_REPLICA_HOST": {},
+			"CACHE_RETRIES":   {Optional: true},
+			"TIMEOUT":         {Value: "5s", HasDefault: true, Description: "request timeout"},
+			"WORKERS":         {Computed: "runtime.NumCPU()"},
 			// other.go (with build tags)
 			"BUILD_TAG_TEST": {},
-		}},
+		},
+		Prefixes: []string{"LABEL_"},
+	},
 	))
 	resF := strings.ReplaceAll(res.ToFile(), "\n", "")
-	is.True(strings.Compare(resF, `BUILD_TAG_TEST=""ENV=""PORT="6969"SECURE="value is marked as optional"`) == 0)
+	is.True(strings.Compare(resF, `BUILD_TAG_TEST=""CACHE_HOST=""CACHE_POOL_SIZE="computed by poolSize"CACHE_PORT="6379"CACHE_RETRIES="value is marked as optional"DB_REPLICA_HOST=""EMPTY_OK=""ENV=""HOSTNAME="computed by os.Hostname"# port to listen onPORT="6969"PREFIX=""SECURE="value is marked as optional"# request timeoutTIMEOUT="5s"WORKERS="computed by runtime.NumCPU()"# LABEL_*`) == 0)
+}
+
+func TestCheck(t *testing.T) {
+	is := is.New(t)
+	res, err := scan.Scan(scan.Config{Dir: "./test_project/"})
+	is.NoErr(err)
+
+	// computed keys are never missing
+	missing, usingDefault := res.Check(map[string]string{"ENV": "dev", "CACHE_HOST": "cache"})
+	is.Equal(missing, []string{"BUILD_TAG_TEST", "DB_REPLICA_HOST", "EMPTY_OK"})
+	is.Equal(usingDefault, []string{
+		"CACHE_POOL_SIZE computed by poolSize",
+		"CACHE_PORT=6379",
+		"HOSTNAME computed by os.Hostname",
+		"PORT=6969",
+		"PREFIX=",
+		"TIMEOUT=5s",
+		"WORKERS computed by runtime.NumCPU()",
+	})
 }
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/taybart/env"
+)
+
+const cachePrefix = "CACHE_"
+
+func init() {
+	cache := env.WithPrefix(cachePrefix)
+	cache.Add([]string{"HOST", "PORT=6379"})
+	cache.AddComputed("POOL_SIZE | int", poolSize)
+
+	env.WithPrefix("DB_").WithPrefix("REPLICA_").Add([]string{"HOST"})
+}
+
+func labels() map[string]string {
+	return env.Prefixed("LABEL_")
+}
+
+func poolSize() (string, error) {
+	return strconv.Itoa(runtime.NumCPU() * 2), nil
+}
+
+var (
+	timeout = env.DurationVar("TIMEOUT").Default(5 * time.Second).Describe("request timeout")
+	retries = env.WithPrefix(cachePrefix).IntVar("RETRIES").Optional().Range(0, 10)
+	workers = env.IntVar("WORKERS").Default(runtime.NumCPU())
+)
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/taybart/env"
 )
@@ -9,9 +10,12 @@ import (
 func main() {
 	env.Add([]string{
 		"ENV",
-		"PORT=6969",
+		"PORT=6969 | int # port to listen on",
 		"SECURE?",
+		"PREFIX=",
+		"EMPTY_OK*",
 	})
+	env.AddComputed("HOSTNAME", os.Hostname)
 
 	if env.Is("ENV", "production") {
 		fmt.Println("HOLY CRAP CALL THE SENIOR")
@@ -1,16 +1,12 @@
 package scan
 
 import (
-	"bufio"
 	"errors"
 	"go/ast"
-	"log"
 	"os"
-	"regexp"
-)
+	"strconv"
 
-var (
-	envRE = regexp.MustCompile(`([[:word:]]+)="?(.*)?"?`)
+	"github.com/taybart/env"
 )
 
 // isIdent: Checks that expr is an idenifier
@@ -38,7 +34,9 @@ func getStringArray(l *ast.CompositeLit) ([]string, error) {
 
 	for _, i := range l.Elts {
 		if v, ok := i.(*ast.BasicLit); ok {
-			arr = append(arr, v.Value)
+			if s, err := strconv.Unquote(v.Value); err == nil {
+				arr = append(arr, s)
+			}
 		}
 	}
 
@@ -59,26 +57,11 @@ func dedupe(in []string) []string {
 
 }
 
-func parseEnvFile(filename string) (envFile map[string]string, err error) {
-	envFile = make(map[string]string)
-
+func parseEnvFile(filename string) (map[string]string, error) {
 	file, err := os.Open(filename)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		res := envRE.FindAllStringSubmatch(scanner.Text(), -1)
-		if len(res) > 0 {
-			envFile[res[0][1]] = res[0][2]
-		}
-	}
-
-	if err = scanner.Err(); err != nil {
-		return
-	}
-
-	return
+	return env.ParseDotenv(file)
 }
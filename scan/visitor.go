@@ -4,10 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"go/ast"
+	"go/constant"
 	"go/parser"
 	"go/token"
+	"go/types"
+	"log"
 	"os"
-	"strings"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/taybart/env"
 )
@@ -15,6 +20,11 @@ import (
 type visitor struct {
 	decls       map[string][]string
 	env         map[string][]string
+	consts      map[string]string      // string constants, for prefixes
+	scopes      map[string]string      // env.Scope variables and their prefix
+	prefixed    []string               // prefixes read with Prefixed/Indexed
+	computed    map[string]string      // keys declared with AddComputed and their function
+	handles     map[*ast.CallExpr]bool // IntVar etc. calls already recorded
 	fset        *token.FileSet
 	packageName string
 	fn          string
@@ -24,9 +34,13 @@ func newVisitor() visitor {
 	// generate tokens
 	fset := token.NewFileSet()
 	return visitor{
-		decls: make(map[string][]string),
-		env:   make(map[string][]string),
-		fset:  fset,
+		decls:    make(map[string][]string),
+		env:      make(map[string][]string),
+		consts:   make(map[string]string),
+		scopes:   make(map[string]string),
+		computed: make(map[string]string),
+		handles:  make(map[*ast.CallExpr]bool),
+		fset:     fset,
 	}
 }
 
@@ -42,7 +56,7 @@ func (v *visitor) Load(filename string) (ast.Node, error) {
 	// check if the package is actually imported
 	usesEnv := false
 	for _, i := range node.Imports {
-		if strings.Contains(i.Path.Value, "github.com/taybart/env") {
+		if i.Path.Value == `"github.com/taybart/env"` {
 			usesEnv = true
 			v.packageName = "env"
 			if i.Name != nil {
@@ -60,11 +74,14 @@ func (v *visitor) Load(filename string) (ast.Node, error) {
 func (v *visitor) Visit(n ast.Node) bool {
 	switch n := n.(type) {
 	case *ast.AssignStmt: // Line contains an assignment with :=
-		for _, name := range n.Lhs {
+		for i, name := range n.Lhs {
 			if ident, ok := name.(*ast.Ident); ok {
 				if ident.Name == "_" {
 					return true
 				}
+				if i < len(n.Rhs) {
+					v.value(ident.Name, n.Rhs[i])
+				}
 				if ident.Obj != nil && ident.Obj.Pos() == ident.Pos() {
 					if compLit, ok := n.Rhs[0].(*ast.CompositeLit); ok {
 						arr, err := getStringArray(compLit)
@@ -81,7 +98,10 @@ func (v *visitor) Visit(n ast.Node) bool {
 			for _, spec := range n.Specs {
 				switch spec := spec.(type) {
 				case *ast.ValueSpec:
-					for _, name := range spec.Names {
+					for i, name := range spec.Names {
+						if i < len(spec.Values) {
+							v.value(name.Name, spec.Values[i])
+						}
 						if vspec, ok := name.Obj.Decl.(*ast.ValueSpec); ok { // get the
 							if len(vspec.Values) > 0 {
 								if cl, ok := vspec.Values[0].(*ast.CompositeLit); ok {
@@ -98,34 +118,332 @@ func (v *visitor) Visit(n ast.Node) bool {
 			}
 		}
 	case *ast.CallExpr: // line contains a function call
-		if isPkgDot(n.Fun, v.packageName, "Add") { // check that function call is env.Set() (handles import renames)
-			switch arg := n.Args[0].(type) {
-			case *ast.CompositeLit: // function was passed anon []string
-				arr, _ := getStringArray(arg)
-				v.env[v.fn] = append(v.env[v.fn], arr...)
-			case *ast.Ident: // function was passed a variable
-				v.env[v.fn] = append(v.env[v.fn], v.decls[arg.Name]...)
+		v.handle(n)
+		sel, ok := n.Fun.(*ast.SelectorExpr)
+		if !ok || len(n.Args) == 0 {
+			return true
+		}
+		if isIdent(sel.Sel, "Prefixed") || isIdent(sel.Sel, "Indexed") {
+			prefix, ok := v.prefix(sel.X)
+			if !ok {
+				return true
 			}
+			if p, ok := v.str(n.Args[0]); ok {
+				v.prefixed = append(v.prefixed, prefix+p)
+			}
+			return true
+		}
+		if isIdent(sel.Sel, "AddComputed") || isIdent(sel.Sel, "EnsureComputed") {
+			v.addComputed(sel.X, n.Args)
+			return true
+		}
+		if !isIdent(sel.Sel, "Add") {
+			return true
+		}
+		// check that function call is env.Add() (handles import renames) or
+		// Add on a scope with a known prefix
+		prefix, ok := v.prefix(sel.X)
+		if !ok {
+			return true
+		}
+		var arr []string
+		switch arg := n.Args[0].(type) {
+		case *ast.CompositeLit: // function was passed anon []string
+			arr, _ = getStringArray(arg)
+		case *ast.Ident: // function was passed a variable
+			arr = v.decls[arg.Name]
+		}
+		for _, k := range arr {
+			v.env[v.fn] = append(v.env[v.fn], prefix+k)
 		}
 	}
 	return true
 }
 
-func (v visitor) EnvToMap() (map[string]string, map[string]bool) {
-	e := []string{}
-	for _, ev := range v.env {
-		e = append(e, ev...)
+// addComputed: Record a key declared with AddComputed and the name of the
+// function computing its default
+func (v *visitor) addComputed(recv ast.Expr, args []ast.Expr) {
+	prefix, ok := v.prefix(recv)
+	if !ok || len(args) != 2 {
+		return
 	}
-	e = dedupe(e)
+	key, ok := v.str(args[0])
+	if !ok {
+		return
+	}
+	spec, err := env.ParseSpec(key)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	name := "func literal"
+	switch fn := args[1].(type) {
+	case *ast.Ident:
+		name = fn.Name
+	case *ast.SelectorExpr:
+		if x, ok := fn.X.(*ast.Ident); ok {
+			name = x.Name + "." + fn.Sel.Name
+		}
+	}
+	v.env[v.fn] = append(v.env[v.fn], prefix+key)
+	v.computed[prefix+spec.Name] = name
+}
 
-	optional := env.GetOptional(e)
+// handleValidators: Functions declaring typed handles and the validator
+// they add to the spec
+var handleValidators = map[string]string{
+	"StringVar":   "",
+	"IntVar":      "int",
+	"FloatVar":    "float",
+	"BoolVar":     "bool",
+	"DurationVar": "duration",
+}
 
-	envmap := make(map[string]string)
-	for _, k := range e {
-		key, val := env.GetDefault(k[1 : len(k)-1])
-		envmap[key] = val
+// handle: Record a typed handle declaration along with its builder chain,
+// env.IntVar("PORT").Default(8080).Describe("HTTP port"). Calls are visited
+// outermost first so the whole chain is seen once.
+func (v *visitor) handle(call *ast.CallExpr) {
+	chain := []*ast.CallExpr{}
+	for {
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return
+		}
+		if validator, ok := handleValidators[sel.Sel.Name]; ok {
+			if v.handles[call] || len(call.Args) != 1 {
+				return
+			}
+			prefix, ok := v.prefix(sel.X)
+			if !ok {
+				return
+			}
+			key, ok := v.str(call.Args[0])
+			if !ok {
+				return
+			}
+			v.handles[call] = true
+			spec := env.Spec{Name: prefix + key}
+			if validator != "" {
+				spec.Validators = append(spec.Validators, env.Validator{Name: validator})
+			}
+			for i := len(chain) - 1; i >= 0; i-- {
+				v.builder(&spec, validator, chain[i])
+			}
+			v.env[v.fn] = append(v.env[v.fn], spec.String())
+			return
+		}
+		inner, ok := sel.X.(*ast.CallExpr)
+		if !ok {
+			return
+		}
+		chain = append(chain, call)
+		call = inner
+	}
+}
+
+// builder: Apply a builder method of a typed handle to spec, kind is the
+// validator of the handle. Arguments that aren't constant are left out, a
+// default that isn't is recorded as computed by its expression.
+func (v *visitor) builder(spec *env.Spec, kind string, call *ast.CallExpr) {
+	method := call.Fun.(*ast.SelectorExpr).Sel.Name
+	if method == "Describe" || method == "Check" {
+		kind = "" // take strings
+	}
+	args := make([]string, len(call.Args))
+	for i, arg := range call.Args {
+		lit, ok := v.literal(arg, kind)
+		if !ok {
+			if method == "Default" {
+				v.computed[spec.Name] = types.ExprString(arg)
+			}
+			return
+		}
+		args[i] = lit
+	}
+	switch method {
+	case "Default":
+		if len(args) == 1 {
+			spec.Default, spec.HasDefault = args[0], true
+		}
+	case "Optional":
+		spec.Optional = true
+	case "AllowEmpty":
+		spec.AllowEmpty = true
+	case "Secret":
+		spec.Secret = true
+	case "Describe":
+		if len(args) == 1 {
+			spec.Description = args[0]
+		}
+	case "Range":
+		spec.Validators = append(spec.Validators, env.Validator{Name: "range", Args: args})
+	case "OneOf":
+		spec.Validators = append(spec.Validators, env.Validator{Name: "oneof", Args: args})
+	case "Check":
+		if len(args) > 0 {
+			spec.Validators = append(spec.Validators, env.Validator{Name: args[0], Args: args[1:]})
+		}
 	}
-	return envmap, optional
+}
+
+// literal: The value of a constant expression formatted the way a handle
+// of kind formats it, ex. 5 * time.Second is 5s for a duration
+func (v *visitor) literal(expr ast.Expr, kind string) (string, bool) {
+	if s, ok := v.str(expr); ok {
+		return s, true
+	}
+	c, ok := v.constant(expr)
+	if !ok {
+		return "", false
+	}
+	switch {
+	case c.Kind() == constant.Bool:
+		return strconv.FormatBool(constant.BoolVal(c)), true
+	case c.Kind() == constant.String:
+		return constant.StringVal(c), true
+	case kind == "duration":
+		n, exact := constant.Int64Val(constant.ToInt(c))
+		return time.Duration(n).String(), exact
+	case kind == "float" || c.Kind() == constant.Float:
+		f, _ := constant.Float64Val(c)
+		return strconv.FormatFloat(f, 'g', -1, 64), true
+	default:
+		return c.ExactString(), true
+	}
+}
+
+// durations: The units of the time package
+var durations = map[string]time.Duration{
+	"Nanosecond":  time.Nanosecond,
+	"Microsecond": time.Microsecond,
+	"Millisecond": time.Millisecond,
+	"Second":      time.Second,
+	"Minute":      time.Minute,
+	"Hour":        time.Hour,
+}
+
+// constant: Fold a constant expression of literals, time units and
+// arithmetic, ex. 2 * time.Minute or 60 * 60
+func (v *visitor) constant(expr ast.Expr) (constant.Value, bool) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		c := constant.MakeFromLiteral(e.Value, e.Kind, 0)
+		return c, c.Kind() != constant.Unknown
+	case *ast.Ident:
+		switch e.Name {
+		case "true", "false":
+			return constant.MakeBool(e.Name == "true"), true
+		}
+	case *ast.ParenExpr:
+		return v.constant(e.X)
+	case *ast.SelectorExpr:
+		if x, ok := e.X.(*ast.Ident); ok && x.Name == "time" {
+			if d, ok := durations[e.Sel.Name]; ok {
+				return constant.MakeInt64(int64(d)), true
+			}
+		}
+	case *ast.CallExpr: // time.Duration(n)
+		if sel, ok := e.Fun.(*ast.SelectorExpr); ok && len(e.Args) == 1 {
+			if x, ok := sel.X.(*ast.Ident); ok && x.Name == "time" && sel.Sel.Name == "Duration" {
+				return v.constant(e.Args[0])
+			}
+		}
+	case *ast.UnaryExpr:
+		if x, ok := v.constant(e.X); ok && (e.Op == token.SUB || e.Op == token.ADD) {
+			return constant.UnaryOp(e.Op, x, 0), true
+		}
+	case *ast.BinaryExpr:
+		x, ok := v.constant(e.X)
+		if !ok {
+			return nil, false
+		}
+		y, ok := v.constant(e.Y)
+		if !ok {
+			return nil, false
+		}
+		op := e.Op
+		switch op {
+		case token.ADD, token.SUB, token.MUL:
+		case token.QUO:
+			if x.Kind() == constant.Int && y.Kind() == constant.Int {
+				if constant.Sign(y) == 0 {
+					return nil, false
+				}
+				op = token.QUO_ASSIGN // integer division
+			}
+		default:
+			return nil, false
+		}
+		if x.Kind() == constant.String || y.Kind() == constant.String ||
+			x.Kind() == constant.Bool || y.Kind() == constant.Bool {
+			return nil, false
+		}
+		return constant.BinaryOp(x, op, y), true
+	}
+	return nil, false
+}
+
+// value: Remember string constants and env.Scope variables assigned to name
+func (v *visitor) value(name string, expr ast.Expr) {
+	if s, ok := v.str(expr); ok {
+		v.consts[name] = s
+	}
+	if _, ok := expr.(*ast.CallExpr); ok {
+		if prefix, ok := v.prefix(expr); ok {
+			v.scopes[name] = prefix
+		}
+	}
+}
+
+// str: Resolve a string literal, constant or concatenation of them
+func (v *visitor) str(expr ast.Expr) (string, bool) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.STRING {
+			return "", false
+		}
+		s, err := strconv.Unquote(e.Value)
+		return s, err == nil
+	case *ast.Ident:
+		s, ok := v.consts[e.Name]
+		return s, ok
+	case *ast.BinaryExpr:
+		if e.Op != token.ADD {
+			return "", false
+		}
+		x, ok := v.str(e.X)
+		if !ok {
+			return "", false
+		}
+		y, ok := v.str(e.Y)
+		return x + y, ok
+	}
+	return "", false
+}
+
+// prefix: Resolve the key prefix of expr, which is either the env package,
+// a scope variable or a call to WithPrefix with a constant prefix
+func (v *visitor) prefix(expr ast.Expr) (string, bool) {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if e.Name == v.packageName {
+			return "", true
+		}
+		prefix, ok := v.scopes[e.Name]
+		return prefix, ok
+	case *ast.CallExpr:
+		sel, ok := e.Fun.(*ast.SelectorExpr)
+		if !ok || !isIdent(sel.Sel, "WithPrefix") || len(e.Args) != 1 {
+			return "", false
+		}
+		outer, ok := v.prefix(sel.X)
+		if !ok {
+			return "", false
+		}
+		inner, ok := v.str(e.Args[0])
+		return outer + inner, ok
+	}
+	return "", false
 }
 
 func (v visitor) Finish() Env {
@@ -135,13 +453,25 @@ func (v visitor) Finish() Env {
 	}
 	e = dedupe(e)
 
-	optional := env.GetOptional(e)
 	ret := NewEnv()
 	for _, k := range e {
-		key, val := env.GetDefault(k[1 : len(k)-1])
-		ret.Values[key] = EnvVar{Value: val, Optional: optional[key], HasDefault: val != ""}
-
+		spec, err := env.ParseSpec(k)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		ret.Values[spec.Name] = EnvVar{
+			Value:       spec.Default,
+			Optional:    spec.Optional,
+			HasDefault:  spec.HasDefault,
+			AllowEmpty:  spec.AllowEmpty,
+			Secret:      spec.Secret,
+			Description: spec.Description,
+			Computed:    v.computed[spec.Name],
+		}
 	}
+	ret.Prefixes = dedupe(v.prefixed)
+	sort.Strings(ret.Prefixes)
 	ret.v = &v
 	return ret
 }
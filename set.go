@@ -0,0 +1,80 @@
+package env
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+/* Set : change key at runtime, replacing os.Setenv. Declared keys are
+ * validated against their spec, so
+ *   env.Add([]string{"PORT=8080 | int"})
+ *   env.Set("PORT", "http") // error, PORT is not an int
+ * The value wins over flags, the process env and files, is written to the
+ * source when DefaultsInProcess is on, and subscribers are notified if it
+ * changed. ProvenanceOf reports FromRuntime.
+ */
+func (e *Environment) Set(key, value string) error {
+	if e.IsFrozen() {
+		return ErrFrozen
+	}
+	for i := 0; i < len(key); i++ {
+		if !isNameByte(key[i], i == 0) {
+			return fmt.Errorf("invalid env name %q", key)
+		}
+	}
+	if spec, ok := e.Spec(key); ok {
+		if err := checkValue(spec, value, true); err != nil {
+			return err
+		}
+	}
+	var old string
+	var inProcess bool
+	e.update(func(r *registry) {
+		// the published registry is still the old one
+		old, _ = e.resolve(key)
+		r.runtime[key] = value
+		inProcess = r.defaultsInProcess
+	})
+	if setter, ok := e.source.(Setter); ok && inProcess {
+		setter.Set(key, value)
+	}
+	if old != value {
+		e.notify(key, old, value)
+	}
+	return nil
+}
+
+// SetInt : Set with an int in its canonical form
+func (e *Environment) SetInt(key string, val int) error {
+	return e.Set(key, strconv.Itoa(val))
+}
+
+// SetBool : Set with true or false
+func (e *Environment) SetBool(key string, val bool) error {
+	return e.Set(key, strconv.FormatBool(val))
+}
+
+// SetDuration : Set with a duration formatted like 1m30s
+func (e *Environment) SetDuration(key string, val time.Duration) error {
+	return e.Set(key, val.String())
+}
+
+// Set : see Environment.Set
+func (s Scope) Set(key, value string) error { return s.environment().Set(s.Key(key), value) }
+
+// SetInt : see Environment.SetInt
+func (s Scope) SetInt(key string, val int) error { return s.environment().SetInt(s.Key(key), val) }
+
+// SetBool : see Environment.SetBool
+func (s Scope) SetBool(key string, val bool) error { return s.environment().SetBool(s.Key(key), val) }
+
+// SetDuration : see Environment.SetDuration
+func (s Scope) SetDuration(key string, val time.Duration) error {
+	return s.environment().SetDuration(s.Key(key), val)
+}
+
+// Set : change the value at runtime, see Environment.Set
+func (v *Var[T]) Set(val T) error {
+	return v.scope.environment().Set(v.spec.Name, v.format(val))
+}
@@ -0,0 +1,68 @@
+package env_test
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/taybart/env"
+	"github.com/taybart/env/envtest"
+)
+
+func TestSet(t *testing.T) {
+	is := is.New(t)
+	e := envtest.New(t, map[string]string{"PORT": "8080", "NAME": "env"})
+	e.Add([]string{"PORT | int", "DEBUG=false | bool", "TIMEOUT=5s | duration", "MODE=dev | oneof(dev,prod)", "NAME"})
+
+	var changes []string
+	e.Subscribe("PORT", func(old, new string) { changes = append(changes, old+"->"+new) })
+
+	is.NoErr(e.SetInt("PORT", 9090))
+	is.Equal(e.Int("PORT"), 9090)
+	is.Equal(e.ProvenanceOf("PORT").Kind, env.FromRuntime)
+	v, _ := e.Source.Lookup("PORT") // written through like defaults
+	is.Equal(v, "9090")
+	is.NoErr(e.SetInt("PORT", 9090)) // unchanged, no notification
+	is.Equal(changes, []string{"8080->9090"})
+
+	is.NoErr(e.SetBool("DEBUG", true))
+	is.True(e.Bool("DEBUG"))
+	is.NoErr(e.SetDuration("TIMEOUT", 90*time.Second))
+	is.Equal(e.Get("TIMEOUT"), "1m30s")
+
+	// checked against the declaration
+	is.True(e.Set("PORT", "http") != nil)
+	is.True(e.Set("MODE", "staging") != nil)
+	is.True(e.Set("NAME", "") != nil)
+	is.True(e.Set("BAD NAME", "x") != nil)
+	is.Equal(e.Int("PORT"), 9090)
+
+	// undeclared keys are set as is
+	is.NoErr(e.Set("EXTRA", "x"))
+	is.Equal(e.Get("EXTRA"), "x")
+
+	// wins over flags
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	is.NoErr(e.BindFlags(fs, nil))
+	is.NoErr(fs.Parse([]string{"-name", "flag"}))
+	is.NoErr(e.Set("NAME", "runtime"))
+	is.Equal(e.Get("NAME"), "runtime")
+
+	is.NoErr(e.Freeze())
+	is.Equal(e.Set("NAME", "frozen"), env.ErrFrozen)
+}
+
+func TestVarSet(t *testing.T) {
+	is := is.New(t)
+	e := envtest.New(t, nil)
+	e.DefaultsInProcess(false)
+	retries := e.IntVar("RETRIES").Default(3).Range(0, 10)
+
+	is.NoErr(retries.Set(5))
+	is.Equal(retries.Get(), 5)
+	_, found := e.Source.Lookup("RETRIES") // only kept in memory
+	is.True(!found)
+	is.True(retries.Set(11) != nil)
+	is.Equal(retries.Get(), 5)
+}
@@ -0,0 +1,94 @@
+package env
+
+import (
+	"os"
+	"strings"
+	"sync"
+)
+
+// Source : where an Environment reads variables from, the process env by default
+type Source interface {
+	Lookup(key string) (string, bool)
+	// Environ returns every variable in the source
+	Environ() map[string]string
+}
+
+// Setter : a Source that can be written to, defaults are written to sources
+// that implement it when DefaultsInProcess is on
+type Setter interface {
+	Set(key, value string) error
+	Unset(key string) error
+}
+
+// OS : the process environment
+var OS Source = osSource{}
+
+type osSource struct{}
+
+func (osSource) Lookup(key string) (string, bool) {
+	return os.LookupEnv(key)
+}
+
+func (osSource) Environ() map[string]string {
+	all := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			all[k] = v
+		}
+	}
+	return all
+}
+
+func (osSource) Set(key, value string) error {
+	return os.Setenv(key, value)
+}
+
+func (osSource) Unset(key string) error {
+	return os.Unsetenv(key)
+}
+
+// MapSource : an in memory Source, safe for concurrent use
+type MapSource struct {
+	mu     sync.RWMutex
+	values map[string]string
+}
+
+// NewMapSource : a Source holding a copy of values
+func NewMapSource(values map[string]string) *MapSource {
+	m := &MapSource{values: make(map[string]string, len(values))}
+	for k, v := range values {
+		m.values[k] = v
+	}
+	return m
+}
+
+func (m *MapSource) Lookup(key string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	val, found := m.values[key]
+	return val, found
+}
+
+func (m *MapSource) Environ() map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	all := make(map[string]string, len(m.values))
+	for k, v := range m.values {
+		all[k] = v
+	}
+	return all
+}
+
+func (m *MapSource) Set(key, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = value
+	return nil
+}
+
+func (m *MapSource) Unset(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, key)
+	return nil
+}
@@ -0,0 +1,449 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/* Spec grammar, as accepted by Add, Ensure and ParseSpec:
+ *
+ *   spec       = name [ flags ] [ "=" default ] { "|" validator } [ "#" description ]
+ *   name       = ( letter | "_" ) { letter | digit | "_" }
+ *   flags      = any of "?" (optional), "*" (may be empty), "!" (secret)
+ *   default    = quoted | bare
+ *   quoted     = a go double quoted string, ex. "has | and # in it"
+ *   bare       = everything up to the first " |" or " #"
+ *   validator  = ident [ "(" arg { "," arg } ")" ]
+ *   arg        = quoted | bare
+ *   bare       = anything but "," and ")", except inside balanced (), []
+ *                or {}. A backslash escapes the next character and is kept
+ *
+ * Built in validators are int, float, bool, duration, url, oneof(a,b,...),
+ * range(lo,hi) of numbers or durations, match(regexp), cert (PEM certificates that have not expired)
+ * and privatekey.
+ *
+ * Whitespace is allowed around validators and before the description.
+ *
+ *   PORT=8080 | int | range(1,65535) # HTTP port
+ *   TOKEN! # api token, never logged
+ *   LOG_LEVEL?=info | oneof(debug,info,warn,error)
+ *   SLUG | match(^[a-z]{1,32}(-[a-z]+)*$)
+ *   GREETING | oneof("hello, world", hi)
+ */
+
+// ErrInvalidSpec is wrapped by every error returned from ParseSpec
+var ErrInvalidSpec = errors.New("invalid env spec")
+
+// Spec : a parsed env declaration
+type Spec struct {
+	Name string
+	// Default is only used when HasDefault is true, it may be empty (NAME=)
+	Default    string
+	HasDefault bool
+	// Optional values default to their zero value when unset (NAME?)
+	Optional bool
+	// AllowEmpty values are required but may be blank (NAME*)
+	AllowEmpty bool
+	// Secret values are redacted wherever env prints them (NAME!)
+	Secret      bool
+	Description string
+	Validators  []Validator
+}
+
+// Validator : a named check run against the value of a spec
+type Validator struct {
+	Name string
+	Args []string
+}
+
+// SpecError : describes where a spec failed to parse
+type SpecError struct {
+	Spec   string
+	Offset int
+	Reason string
+}
+
+func (e *SpecError) Error() string {
+	return fmt.Sprintf("%v %q at offset %d: %s", ErrInvalidSpec, e.Spec, e.Offset, e.Reason)
+}
+
+func (e *SpecError) Unwrap() error {
+	return ErrInvalidSpec
+}
+
+// ParseSpec : parse a single env declaration, see the grammar above
+func ParseSpec(entry string) (Spec, error) {
+	var s Spec
+	fail := func(i int, format string, args ...any) (Spec, error) {
+		return Spec{}, &SpecError{Spec: entry, Offset: i, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	i := 0
+	for i < len(entry) && isNameByte(entry[i], i == 0) {
+		i++
+	}
+	if i == 0 {
+		return fail(0, "expected variable name")
+	}
+	s.Name = entry[:i]
+
+	for ; i < len(entry); i++ {
+		var flag *bool
+		switch entry[i] {
+		case '?':
+			flag = &s.Optional
+		case '*':
+			flag = &s.AllowEmpty
+		case '!':
+			flag = &s.Secret
+		}
+		if flag == nil {
+			break
+		}
+		if *flag {
+			return fail(i, "duplicate flag %q", entry[i])
+		}
+		*flag = true
+	}
+
+	if i < len(entry) && entry[i] == '=' {
+		i++
+		s.HasDefault = true
+		if i < len(entry) && entry[i] == '"' {
+			quoted, err := strconv.QuotedPrefix(entry[i:])
+			if err != nil {
+				return fail(i, "unterminated quoted default")
+			}
+			s.Default, _ = strconv.Unquote(quoted)
+			i += len(quoted)
+		} else {
+			end := len(entry)
+			for _, sep := range []string{" |", " #"} {
+				if j := strings.Index(entry[i:], sep); j >= 0 && i+j < end {
+					end = i + j
+				}
+			}
+			s.Default = entry[i:end]
+			i = end
+		}
+	}
+
+	for i < len(entry) {
+		switch entry[i] {
+		case ' ', '\t':
+			i++
+		case '#':
+			s.Description = strings.TrimSpace(entry[i+1:])
+			i = len(entry)
+		case '|':
+			i++
+			for i < len(entry) && (entry[i] == ' ' || entry[i] == '\t') {
+				i++
+			}
+			start := i
+			for i < len(entry) && isNameByte(entry[i], i == start) {
+				i++
+			}
+			if i == start {
+				return fail(i, "expected validator name")
+			}
+			v := Validator{Name: entry[start:i]}
+			if i < len(entry) && entry[i] == '(' {
+				args, end, err := parseArgs(entry, i)
+				if err != nil {
+					return fail(end, "%v to %s", err, v.Name)
+				}
+				v.Args, i = args, end
+			}
+			if err := checkValidator(v); err != nil {
+				return fail(start, "%v", err)
+			}
+			s.Validators = append(s.Validators, v)
+		default:
+			return fail(i, "unexpected %q", entry[i])
+		}
+	}
+	return s, nil
+}
+
+// Validate : run every validator of the spec against val
+func (s Spec) Validate(val string) error {
+	for _, v := range s.Validators {
+		fn, ok := (*validators.Load())[v.Name]
+		if !ok {
+			return fmt.Errorf("%s: unknown validator %s", s.Name, v.Name)
+		}
+		if err := fn.check(val, v.Args); err != nil {
+			return fmt.Errorf("%s failed %s: %w", s.Name, v, err)
+		}
+	}
+	return nil
+}
+
+// String : the canonical form of the spec, parses back to the same Spec
+func (s Spec) String() string {
+	var sb strings.Builder
+	sb.WriteString(s.Name)
+	if s.Optional {
+		sb.WriteByte('?')
+	}
+	if s.AllowEmpty {
+		sb.WriteByte('*')
+	}
+	if s.Secret {
+		sb.WriteByte('!')
+	}
+	if s.HasDefault {
+		sb.WriteByte('=')
+		if strings.ContainsAny(s.Default, `"|#`) || strings.TrimSpace(s.Default) != s.Default {
+			sb.WriteString(strconv.Quote(s.Default))
+		} else {
+			sb.WriteString(s.Default)
+		}
+	}
+	for _, v := range s.Validators {
+		sb.WriteString(" | ")
+		sb.WriteString(v.String())
+	}
+	if s.Description != "" {
+		sb.WriteString(" # ")
+		sb.WriteString(s.Description)
+	}
+	return sb.String()
+}
+
+func (v Validator) String() string {
+	if len(v.Args) == 0 {
+		return v.Name
+	}
+	args := make([]string, len(v.Args))
+	for i, arg := range v.Args {
+		args[i] = arg
+		// quote anything that would not parse back as is
+		if parsed, _, err := parseArgs("("+arg+")", 0); err != nil || len(parsed) != 1 || parsed[0] != arg {
+			args[i] = strconv.Quote(arg)
+		}
+	}
+	return fmt.Sprintf("%s(%s)", v.Name, strings.Join(args, ","))
+}
+
+// parseArgs : the arguments of a validator starting at the "(" at i, and
+// the offset after the closing ")"
+func parseArgs(entry string, i int) ([]string, int, error) {
+	args := []string{}
+	i++ // (
+	for {
+		for i < len(entry) && (entry[i] == ' ' || entry[i] == '\t') {
+			i++
+		}
+		var arg string
+		if i < len(entry) && entry[i] == '"' {
+			quoted, err := strconv.QuotedPrefix(entry[i:])
+			if err != nil {
+				return nil, i, errors.New("unterminated quoted argument")
+			}
+			arg, _ = strconv.Unquote(quoted)
+			i += len(quoted)
+			for i < len(entry) && (entry[i] == ' ' || entry[i] == '\t') {
+				i++
+			}
+		} else {
+			start, depth := i, 0
+		bare:
+			for ; i < len(entry); i++ {
+				switch entry[i] {
+				case '\\':
+					i++ // kept, regexps need it
+				case '(', '[', '{':
+					depth++
+				case ']', '}':
+					if depth > 0 {
+						depth--
+					}
+				case ')':
+					if depth == 0 {
+						break bare
+					}
+					depth--
+				case ',':
+					if depth == 0 {
+						break bare
+					}
+				}
+			}
+			if i > len(entry) {
+				i = len(entry)
+			}
+			arg = strings.TrimSpace(entry[start:i])
+		}
+		args = append(args, arg)
+		switch {
+		case i >= len(entry):
+			return nil, i, errors.New("missing closing )")
+		case entry[i] == ',':
+			i++
+		case entry[i] == ')':
+			return args, i + 1, nil
+		default:
+			return nil, i, fmt.Errorf("unexpected %q in arguments", entry[i])
+		}
+	}
+}
+
+func isNameByte(c byte, first bool) bool {
+	switch {
+	case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		return true
+	case '0' <= c && c <= '9':
+		return !first
+	}
+	return false
+}
+
+type validator struct {
+	// nargs is the number of arguments required, -1 for one or more and
+	// -2 for any number
+	nargs int
+	check func(val string, args []string) error
+}
+
+// validators by name, copy on write like the registry so specs are checked
+// without locking
+var (
+	validatorsMu sync.Mutex // serializes RegisterValidator
+	validators   atomic.Pointer[map[string]validator]
+)
+
+func init() {
+	validators.Store(&builtinValidators)
+}
+
+var builtinValidators = map[string]validator{
+	"int": {0, func(val string, _ []string) error {
+		_, err := strconv.Atoi(val)
+		return err
+	}},
+	"float": {0, func(val string, _ []string) error {
+		_, err := strconv.ParseFloat(val, 64)
+		return err
+	}},
+	"bool": {0, func(val string, _ []string) error {
+		_, err := strconv.ParseBool(val)
+		return err
+	}},
+	"duration": {0, func(val string, _ []string) error {
+		_, err := time.ParseDuration(val)
+		return err
+	}},
+	"url": {0, func(val string, _ []string) error {
+		u, err := url.Parse(val)
+		if err != nil {
+			return err
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("%q is not an absolute url", val)
+		}
+		return nil
+	}},
+	"oneof": {-1, func(val string, args []string) error {
+		for _, a := range args {
+			if val == a {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of %v", val, args)
+	}},
+	"range": {2, func(val string, args []string) error {
+		lo, hi, durations, err := rangeBounds(args)
+		if err != nil {
+			return err
+		}
+		var n float64
+		if durations {
+			d, err := time.ParseDuration(val)
+			if err != nil {
+				return err
+			}
+			n = float64(d)
+		} else if n, err = strconv.ParseFloat(val, 64); err != nil {
+			return err
+		}
+		if n < lo || n > hi {
+			return fmt.Errorf("%s is not between %s and %s", val, args[0], args[1])
+		}
+		return nil
+	}},
+	"match": {1, func(val string, args []string) error {
+		re, err := regexp.Compile(args[0])
+		if err != nil {
+			return err
+		}
+		if !re.MatchString(val) {
+			return fmt.Errorf("%q does not match %s", val, args[0])
+		}
+		return nil
+	}},
+	"cert":       {0, checkCert},
+	"privatekey": {0, checkPrivateKey},
+}
+
+// RegisterValidator : make a custom validator available to specs under name.
+// It is passed the value and the arguments from the spec.
+func RegisterValidator(name string, fn func(val string, args []string) error) {
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+	current := *validators.Load()
+	next := make(map[string]validator, len(current)+1)
+	for k, v := range current {
+		next[k] = v
+	}
+	next[name] = validator{nargs: -2, check: fn}
+	validators.Store(&next)
+}
+
+func checkValidator(v Validator) error {
+	fn, ok := (*validators.Load())[v.Name]
+	if !ok {
+		return fmt.Errorf("unknown validator %s", v.Name)
+	}
+	switch {
+	case fn.nargs == -1 && len(v.Args) == 0:
+		return fmt.Errorf("%s needs at least one argument", v.Name)
+	case fn.nargs >= 0 && len(v.Args) != fn.nargs:
+		return fmt.Errorf("%s takes %d arguments, got %d", v.Name, fn.nargs, len(v.Args))
+	}
+	switch v.Name {
+	case "match":
+		if _, err := regexp.Compile(v.Args[0]); err != nil {
+			return err
+		}
+	case "range":
+		if _, _, _, err := rangeBounds(v.Args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rangeBounds : the bounds of a range, both numbers or both durations like
+// range(1s,1m). Durations are returned in nanoseconds.
+func rangeBounds(args []string) (lo, hi float64, durations bool, err error) {
+	lo, errLo := strconv.ParseFloat(args[0], 64)
+	hi, errHi := strconv.ParseFloat(args[1], 64)
+	if errLo == nil && errHi == nil {
+		return lo, hi, false, nil
+	}
+	dlo, errLo := time.ParseDuration(args[0])
+	dhi, errHi := time.ParseDuration(args[1])
+	if errLo == nil && errHi == nil {
+		return float64(dlo), float64(dhi), true, nil
+	}
+	return 0, 0, false, fmt.Errorf("range bounds must be numbers or durations, got %s and %s", args[0], args[1])
+}
@@ -0,0 +1,64 @@
+package env_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/taybart/env"
+	"github.com/taybart/env/envtest"
+)
+
+func TestParseSpec(t *testing.T) {
+	is := is.New(t)
+
+	tests := []struct {
+		in   string
+		want env.Spec
+	}{
+		{"NAME", env.Spec{Name: "NAME"}},
+		{"NAME?", env.Spec{Name: "NAME", Optional: true}},
+		{"NAME*", env.Spec{Name: "NAME", AllowEmpty: true}},
+		{"NAME=", env.Spec{Name: "NAME", HasDefault: true}},
+		{"NAME=what?", env.Spec{Name: "NAME", Default: "what?", HasDefault: true}},
+		{"NAME?=info", env.Spec{Name: "NAME", Default: "info", Optional: true, HasDefault: true}},
+		{`NAME!="a | b # c"`, env.Spec{Name: "NAME", Default: "a | b # c", HasDefault: true, Secret: true}},
+		{"NAME=#fff # color", env.Spec{Name: "NAME", Default: "#fff", HasDefault: true, Description: "color"}},
+		{
+			"PORT=8080 | int | range(1, 65535) # HTTP port",
+			env.Spec{
+				Name: "PORT", Default: "8080", HasDefault: true, Description: "HTTP port",
+				Validators: []env.Validator{{Name: "int"}, {Name: "range", Args: []string{"1", "65535"}}},
+			},
+		},
+		{`NAME | match(^(a|b)$)`, env.Spec{Name: "NAME", Validators: []env.Validator{{Name: "match", Args: []string{"^(a|b)$"}}}}},
+		{`NAME | match(^a{1,3}$)`, env.Spec{Name: "NAME", Validators: []env.Validator{{Name: "match", Args: []string{"^a{1,3}$"}}}}},
+		{`NAME | match(^\)+$)`, env.Spec{Name: "NAME", Validators: []env.Validator{{Name: "match", Args: []string{`^\)+$`}}}}},
+		{`NAME | match("^[,)]+$") # quoted`, env.Spec{Name: "NAME", Description: "quoted", Validators: []env.Validator{{Name: "match", Args: []string{"^[,)]+$"}}}}},
+		{`NAME | oneof("a, b", c)`, env.Spec{Name: "NAME", Validators: []env.Validator{{Name: "oneof", Args: []string{"a, b", "c"}}}}},
+	}
+	for _, tt := range tests {
+		got, err := env.ParseSpec(tt.in)
+		is.NoErr(err)
+		is.Equal(got, tt.want)
+		// canonical form parses back to the same spec
+		again, err := env.ParseSpec(got.String())
+		is.NoErr(err)
+		is.Equal(again, got)
+	}
+
+	for _, in := range []string{"", "=oops", "1NAME", "NAME??", "NAME | nope", "NAME | range(1)", "NAME | range(a,z)", `NAME="open`, "NAME trailing", "NAME | match(^(a$)", `NAME | oneof("a" b)`} {
+		_, err := env.ParseSpec(in)
+		is.True(errors.Is(err, env.ErrInvalidSpec)) // should fail to parse
+	}
+}
+
+func TestValidators(t *testing.T) {
+	is := is.New(t)
+	e := envtest.Global(t, nil)
+	e.Setenv("TEST_VALIDATORS", "99999")
+
+	is.True(env.Ensure([]string{"TEST_VALIDATORS | int | range(1,65535)"}) != nil)
+	is.NoErr(env.Ensure([]string{"TEST_VALIDATORS | int | range(1,99999)"}))
+	is.True(env.Ensure([]string{"TEST_VALIDATORS_BAD_DEFAULT=nope | bool"}) != nil)
+}
@@ -0,0 +1,224 @@
+package env
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// the Environment used by the package level functions
+var std atomic.Pointer[Environment]
+
+func init() {
+	std.Store(New(OS))
+}
+
+// Default : the Environment used by the package level functions, reading
+// from the process env
+func Default() *Environment {
+	return std.Load()
+}
+
+// SetDefault : replace the Environment used by the package level functions,
+// returns the previous one. Meant for tests, see the envtest package.
+func SetDefault(e *Environment) (previous *Environment) {
+	return std.Swap(e)
+}
+
+/* Add : declare environment variables for use later. This is global to the project
+ * required -> NAME // must be set and not empty
+ * required_may_be_empty -> NAME* // must be set, may be empty
+ * with_default -> NAME=taybart // default used when NAME is unset
+ * empty_default -> NAME= // defaults to an empty string
+ * optional -> NAME? // defaults to zero value
+ * see ParseSpec for the full grammar
+ */
+func Add(keys []string) { Default().Add(keys) }
+
+// Ensure : check that env vars are defined, set default, mark optional
+func Ensure(keys []string) error { return Default().Ensure(keys) }
+
+// DefaultsInProcess : see Environment.DefaultsInProcess
+func DefaultsInProcess(set bool) { Default().DefaultsInProcess(set) }
+
+// IsDefault : returns if the value of key comes from its declared default
+func IsDefault(key string) bool { return Default().IsDefault(key) }
+
+// Has : see if env var defined, an empty string counts as defined
+func Has(key string) bool { return Default().Has(key) }
+
+// Is : returns if the variable _is_ the string
+func Is(key, compare string) bool { return Default().Is(key, compare) }
+
+// Get : returns the environment value as a string
+func Get(key string) string { return Default().Get(key) }
+
+// Decode : returns the environment value as decoded bytes, see Environment.Decode
+func Decode(key string, enc ...Encoding) ([]byte, error) { return Default().Decode(key, enc...) }
+
+// DecodeTo : see Environment.DecodeTo
+func DecodeTo(key string, out any, enc ...Encoding) error {
+	return Default().DecodeTo(key, out, enc...)
+}
+
+// Int : returns the key as an int or panics
+func Int(key string) int { return Default().Int(key) }
+
+// Bool : returns the env var as its value, or false if it doesn't exist
+func Bool(key string) bool { return Default().Bool(key) }
+
+// IsSet : returns if the environment variable is defined and not a blank string
+func IsSet(key string) bool { return Default().IsSet(key) }
+
+// JSON : returns the environment value marshalled to input
+func JSON(key string, input any) error { return Default().JSON(key, input) }
+
+// JSONWith : see Environment.JSONWith
+func JSONWith(key string, input any, opts JSONOptions) error {
+	return Default().JSONWith(key, input, opts)
+}
+
+// WithPrefix : returns a Scope that prepends prefix to every key
+func WithPrefix(prefix string) Scope { return Default().WithPrefix(prefix) }
+
+// Prefixed : see Environment.Prefixed
+func Prefixed(prefix string) map[string]string { return Default().Prefixed(prefix) }
+
+// Indexed : see Environment.Indexed
+func Indexed(prefix string, out any) error { return Default().Indexed(prefix, out) }
+
+// UsageReport : see Environment.UsageReport
+func UsageReport() Usage { return Default().UsageReport() }
+
+// DumpUsage : write the usage report to w, useful as `defer env.DumpUsage(os.Stderr)`
+func DumpUsage(w io.Writer) { Default().DumpUsage(w) }
+
+// DumpUsageOnSignal : see Environment.DumpUsageOnSignal
+func DumpUsageOnSignal(w io.Writer, sigs ...os.Signal) (stop func()) {
+	return Default().DumpUsageOnSignal(w, sigs...)
+}
+
+// Freeze : see Environment.Freeze
+func Freeze() error { return Default().Freeze() }
+
+// Unfreeze : go back to reading the live process environment
+func Unfreeze() { Default().Unfreeze() }
+
+// IsFrozen : returns if getters are being served from a snapshot
+func IsFrozen() bool { return Default().IsFrozen() }
+
+// Drifted : returns declared keys that changed in the process env since Freeze
+func Drifted() []Drift { return Default().Drifted() }
+
+// CheckDrift : see Environment.CheckDrift
+func CheckDrift() error { return Default().CheckDrift() }
+
+// ProvenanceOf : returns where the current value of key comes from
+func ProvenanceOf(key string) Provenance { return Default().ProvenanceOf(key) }
+
+// BindFlags : see Environment.BindFlags
+func BindFlags(fs *flag.FlagSet, keys []string) error { return Default().BindFlags(fs, keys) }
+
+// LoadFile : see Environment.LoadFile
+func LoadFile(path string) error { return Default().LoadFile(path) }
+
+// LoadProfile : see Environment.LoadProfile
+func LoadProfile(cfg ProfileConfig) ([]string, error) { return Default().LoadProfile(cfg) }
+
+// ConfigReport : describe the current configuration, secret values are redacted
+func ConfigReport() Report { return Default().ConfigReport() }
+
+// Subscribe : see Environment.Subscribe
+func Subscribe(key string, fn func(old, new string)) (unsubscribe func()) {
+	return Default().Subscribe(key, fn)
+}
+
+// LastReload : when the env files were last reloaded, zero if never
+func LastReload() time.Time { return Default().LastReload() }
+
+// Reload : see Environment.Reload
+func Reload() error { return Default().Reload() }
+
+// Watch : see Environment.Watch
+func Watch(interval time.Duration) (stop func()) { return Default().Watch(interval) }
+
+// Environ : see Environment.Environ
+func Environ(opts EnvironOptions) []string { return Default().Environ(opts) }
+
+// Dump : see Environment.Dump
+func Dump(w io.Writer, format DumpFormat) error { return Default().Dump(w, format) }
+
+// AddComputed : see Environment.AddComputed
+func AddComputed(key string, fn func() (string, error)) { Default().AddComputed(key, fn) }
+
+// EnsureComputed : see Environment.EnsureComputed
+func EnsureComputed(key string, fn func() (string, error)) error {
+	return Default().EnsureComputed(key, fn)
+}
+
+// StringVar : see Environment.StringVar
+func StringVar(key string) *Var[string] { return Scope{}.StringVar(key) }
+
+// IntVar : see Environment.IntVar
+func IntVar(key string) *Var[int] { return Scope{}.IntVar(key) }
+
+// FloatVar : see Environment.FloatVar
+func FloatVar(key string) *Var[float64] { return Scope{}.FloatVar(key) }
+
+// BoolVar : see Environment.BoolVar
+func BoolVar(key string) *Var[bool] { return Scope{}.BoolVar(key) }
+
+// DurationVar : see Environment.DurationVar
+func DurationVar(key string) *Var[time.Duration] { return Scope{}.DurationVar(key) }
+
+// Validate : see Environment.Validate
+func Validate() error { return Default().Validate() }
+
+// ReadKey : see Environment.ReadKey
+func ReadKey(ks KeySource) (string, error) { return Default().ReadKey(ks) }
+
+// LoadEncryptedFile : see Environment.LoadEncryptedFile
+func LoadEncryptedFile(path string, ks KeySource) error {
+	return Default().LoadEncryptedFile(path, ks)
+}
+
+// Certificates : see Environment.Certificates
+func Certificates(key string) ([]*x509.Certificate, error) { return Default().Certificates(key) }
+
+// CertPool : see Environment.CertPool
+func CertPool(key string) (*x509.CertPool, error) { return Default().CertPool(key) }
+
+// PrivateKey : see Environment.PrivateKey
+func PrivateKey(key string) (crypto.PrivateKey, error) { return Default().PrivateKey(key) }
+
+// EnsureTLS : see Environment.EnsureTLS
+func EnsureTLS(keys TLSKeys) error { return Default().EnsureTLS(keys) }
+
+// AddTLS : see Environment.AddTLS
+func AddTLS(keys TLSKeys) { Default().AddTLS(keys) }
+
+// TLSConfig : see Environment.TLSConfig
+func TLSConfig(keys TLSKeys) (*tls.Config, error) { return Default().TLSConfig(keys) }
+
+// LoadJSONFile : see Environment.LoadJSONFile
+func LoadJSONFile(path string) error { return Default().LoadJSONFile(path) }
+
+// Set : see Environment.Set
+func Set(key, value string) error { return Default().Set(key, value) }
+
+// SetInt : see Environment.SetInt
+func SetInt(key string, val int) error { return Default().SetInt(key, val) }
+
+// SetBool : see Environment.SetBool
+func SetBool(key string, val bool) error { return Default().SetBool(key, val) }
+
+// SetDuration : see Environment.SetDuration
+func SetDuration(key string, val time.Duration) error { return Default().SetDuration(key, val) }
+
+// Render : see Environment.Render
+func Render(text string, w io.Writer) error { return Default().Render(text, w) }
@@ -0,0 +1,277 @@
+package env
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/taybart/log"
+)
+
+/* pemBytes : PEM data from a value holding any of
+ *   -----BEGIN CERTIFICATE----- ...   inline, newlines may be escaped as \n
+ *   LS0tLS1CRUdJTi...                 base64 of the PEM
+ *   /etc/ssl/server.pem               a path to a PEM file
+ */
+func pemBytes(val string) ([]byte, error) {
+	val = strings.TrimSpace(val)
+	if strings.HasPrefix(val, "-----BEGIN") {
+		if !strings.Contains(val, "\n") {
+			val = strings.ReplaceAll(val, `\n`, "\n")
+		}
+		return []byte(val), nil
+	}
+	if decoded, err := base64.StdEncoding.DecodeString(val); err == nil &&
+		strings.HasPrefix(strings.TrimSpace(string(decoded)), "-----BEGIN") {
+		return decoded, nil
+	}
+	data, err := os.ReadFile(val)
+	if err != nil {
+		// leave the path out, it might be a mangled secret
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			err = pathErr.Err
+		}
+		return nil, fmt.Errorf("not PEM, base64 PEM or a readable file: %w", err)
+	}
+	return data, nil
+}
+
+// parseCerts : every certificate in a PEM value
+func parseCerts(val string) ([]*x509.Certificate, error) {
+	data, err := pemBytes(val)
+	if err != nil {
+		return nil, err
+	}
+	certs := []*x509.Certificate{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	return certs, nil
+}
+
+// parsePrivateKey : the first private key in a PEM value, PKCS#8, PKCS#1 or EC
+func parsePrivateKey(val string) (crypto.PrivateKey, error) {
+	data, err := pemBytes(val)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("no private key found")
+		}
+		if !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			continue
+		}
+		if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+			return key, nil
+		}
+		if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+			return key, nil
+		}
+		if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+			return key, nil
+		}
+		return nil, fmt.Errorf("unsupported %s", block.Type)
+	}
+}
+
+// checkCert : the cert validator, certificates must parse and not be expired
+func checkCert(val string, _ []string) error {
+	certs, err := parseCerts(val)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, cert := range certs {
+		if now.After(cert.NotAfter) {
+			return fmt.Errorf("certificate %q expired at %s", cert.Subject.CommonName, cert.NotAfter.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// checkPrivateKey : the privatekey validator
+func checkPrivateKey(val string, _ []string) error {
+	_, err := parsePrivateKey(val)
+	return err
+}
+
+// Certificates : returns the PEM certificates in key
+func (e *Environment) Certificates(key string) ([]*x509.Certificate, error) {
+	if val, found := e.lookup(key); found {
+		certs, err := parseCerts(val)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		return certs, nil
+	}
+	if e.reg().specs[key].Optional {
+		return nil, nil
+	}
+
+	log.Fatal("Trying to retrieve uninitialized environment variable:", key)
+	return nil, nil
+}
+
+// CertPool : returns a pool of the PEM certificates in key, ex. a CA bundle
+func (e *Environment) CertPool(key string) (*x509.CertPool, error) {
+	certs, err := e.Certificates(key)
+	if certs == nil || err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	for _, cert := range certs {
+		pool.AddCert(cert)
+	}
+	return pool, nil
+}
+
+// PrivateKey : returns the PEM private key in key
+func (e *Environment) PrivateKey(key string) (crypto.PrivateKey, error) {
+	if val, found := e.lookup(key); found {
+		pk, err := parsePrivateKey(val)
+		if err != nil {
+			// never include the value, it is a private key
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		return pk, nil
+	}
+	if e.reg().specs[key].Optional {
+		return nil, nil
+	}
+
+	log.Fatal("Trying to retrieve uninitialized environment variable:", key)
+	return nil, nil
+}
+
+// TLSKeys : the variables holding a certificate, its key and optionally a CA
+type TLSKeys struct {
+	Cert string
+	Key  string
+	// CA bundle used as RootCAs and ClientCAs
+	CA string
+}
+
+/* EnsureTLS : declare the variables in keys and check the certificate matches
+ * the key and has not expired
+ *   env.EnsureTLS(env.TLSKeys{Cert: "TLS_CERT", Key: "TLS_KEY", CA: "TLS_CA"})
+ * Cert and Key are required, the key is secret and CA is optional.
+ */
+func (e *Environment) EnsureTLS(keys TLSKeys) error {
+	return e.WithPrefix("").EnsureTLS(keys)
+}
+
+// AddTLS : same as EnsureTLS but panics on error, like Add
+func (e *Environment) AddTLS(keys TLSKeys) {
+	if err := e.EnsureTLS(keys); err != nil {
+		panic(err)
+	}
+}
+
+// TLSConfig : a tls.Config with the certificate from keys, and the CA as
+// RootCAs and ClientCAs when set. Set ClientAuth for mutual TLS.
+func (e *Environment) TLSConfig(keys TLSKeys) (*tls.Config, error) {
+	return e.WithPrefix("").TLSConfig(keys)
+}
+
+// EnsureTLS : same as env.EnsureTLS with the keys prefixed
+func (s Scope) EnsureTLS(keys TLSKeys) error {
+	specs := []string{keys.Cert + " | cert", keys.Key + "! | privatekey"}
+	if keys.CA != "" {
+		specs = append(specs, keys.CA+"? | cert")
+	}
+	if err := s.Ensure(specs); err != nil {
+		return err
+	}
+	_, err := s.keyPair(keys)
+	return err
+}
+
+// AddTLS : same as env.AddTLS with the keys prefixed
+func (s Scope) AddTLS(keys TLSKeys) {
+	if err := s.EnsureTLS(keys); err != nil {
+		panic(err)
+	}
+}
+
+// TLSConfig : same as env.TLSConfig with the keys prefixed
+func (s Scope) TLSConfig(keys TLSKeys) (*tls.Config, error) {
+	pair, err := s.keyPair(keys)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{pair},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if keys.CA != "" {
+		pool, err := s.CertPool(keys.CA)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs, cfg.ClientCAs = pool, pool
+	}
+	return cfg, nil
+}
+
+// keyPair : load the certificate and key, checking that they match
+func (s Scope) keyPair(keys TLSKeys) (tls.Certificate, error) {
+	e := s.environment()
+	cert, key := s.Key(keys.Cert), s.Key(keys.Key)
+	certVal, _ := e.lookup(cert)
+	keyVal, _ := e.lookup(key)
+	certPEM, err := pemBytes(certVal)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("%s: %w", cert, err)
+	}
+	keyPEM, err := pemBytes(keyVal)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("%s: %w", key, err)
+	}
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("%s and %s: %w", cert, key, err)
+	}
+	return pair, nil
+}
+
+// Certificates : see env.Certificates
+func (s Scope) Certificates(key string) ([]*x509.Certificate, error) {
+	return s.environment().Certificates(s.Key(key))
+}
+
+// CertPool : see env.CertPool
+func (s Scope) CertPool(key string) (*x509.CertPool, error) {
+	return s.environment().CertPool(s.Key(key))
+}
+
+// PrivateKey : see env.PrivateKey
+func (s Scope) PrivateKey(key string) (crypto.PrivateKey, error) {
+	return s.environment().PrivateKey(s.Key(key))
+}
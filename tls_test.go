@@ -0,0 +1,96 @@
+package env_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/taybart/env"
+	"github.com/taybart/env/envtest"
+)
+
+// selfSigned : a PEM certificate and key valid until notAfter
+func selfSigned(t *testing.T, notAfter time.Time) (certPEM, keyPEM string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    notAfter.Add(-48 * time.Hour),
+		NotAfter:     notAfter,
+		IsCA:         true,
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	keyPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+	return certPEM, keyPEM
+}
+
+func TestTLS(t *testing.T) {
+	is := is.New(t)
+	certPEM, keyPEM := selfSigned(t, time.Now().Add(24*time.Hour))
+	keyFile := filepath.Join(t.TempDir(), "key.pem")
+	is.NoErr(os.WriteFile(keyFile, []byte(keyPEM), 0o600))
+
+	e := envtest.New(t, map[string]string{
+		"TLS_CERT": strings.ReplaceAll(certPEM, "\n", `\n`), // escaped newlines
+		"TLS_KEY":  keyFile,                                 // a path
+		"TLS_CA":   base64.StdEncoding.EncodeToString([]byte(certPEM)),
+	})
+	keys := env.TLSKeys{Cert: "TLS_CERT", Key: "TLS_KEY", CA: "TLS_CA"}
+	is.NoErr(e.EnsureTLS(keys))
+	spec, _ := e.Spec("TLS_KEY")
+	is.True(spec.Secret)
+
+	cfg, err := e.TLSConfig(keys)
+	is.NoErr(err)
+	is.Equal(len(cfg.Certificates), 1)
+	is.True(cfg.RootCAs != nil)
+
+	certs, err := e.Certificates("TLS_CA")
+	is.NoErr(err)
+	is.Equal(certs[0].Subject.CommonName, "test")
+	pk, err := e.PrivateKey("TLS_KEY")
+	is.NoErr(err)
+	_, ok := pk.(*ecdsa.PrivateKey)
+	is.True(ok)
+
+	// a key that doesn't belong to the cert
+	_, otherKey := selfSigned(t, time.Now().Add(time.Hour))
+	mismatched := envtest.New(t, map[string]string{"CERT": certPEM, "KEY": otherKey})
+	is.True(mismatched.EnsureTLS(env.TLSKeys{Cert: "CERT", Key: "KEY"}) != nil)
+
+	// expired certificates fail the cert validator
+	expiredCert, expiredKey := selfSigned(t, time.Now().Add(-time.Hour))
+	expired := envtest.New(t, map[string]string{"CERT": expiredCert, "KEY": expiredKey})
+	err = expired.EnsureTLS(env.TLSKeys{Cert: "CERT", Key: "KEY"})
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), "expired"))
+
+	bad := envtest.New(t, map[string]string{"CA": "not a cert"})
+	err = bad.Ensure([]string{"CA | cert"})
+	is.True(err != nil)
+	is.True(!strings.Contains(err.Error(), "not a cert")) // values are not echoed
+}
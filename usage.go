@@ -0,0 +1,108 @@
+package env
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Usage : read counts for the environment gathered while the program ran
+type Usage struct {
+	// Reads is the number of times each key was read through a getter
+	Reads map[string]int
+	// Unused keys were declared with Add/Ensure but never read
+	Unused []string
+	// Undeclared keys were read but never declared with Add/Ensure
+	Undeclared []string
+}
+
+// declare : mark key as declared so it shows up in the usage report
+func (e *Environment) declare(key string) {
+	e.usageMu.Lock()
+	defer e.usageMu.Unlock()
+	e.declared[key] = true
+}
+
+// seen : keys found by enumerating the env (Prefixed, Indexed) count as
+// declared and read
+func (e *Environment) seen(key string) {
+	e.declare(key)
+	e.recordRead(key)
+}
+
+// recordRead : count a read of key, lock free once key has been read before
+func (e *Environment) recordRead(key string) {
+	n, ok := e.reads.Load(key)
+	if !ok {
+		n, _ = e.reads.LoadOrStore(key, new(atomic.Int64))
+	}
+	n.(*atomic.Int64).Add(1)
+}
+
+// UsageReport : returns which declared keys were never read and which
+// undeclared keys were read
+func (e *Environment) UsageReport() Usage {
+	e.usageMu.Lock()
+	defer e.usageMu.Unlock()
+
+	u := Usage{Reads: make(map[string]int)}
+	e.reads.Range(func(k, n any) bool {
+		u.Reads[k.(string)] = int(n.(*atomic.Int64).Load())
+		if !e.declared[k.(string)] {
+			u.Undeclared = append(u.Undeclared, k.(string))
+		}
+		return true
+	})
+	for k := range e.declared {
+		if u.Reads[k] == 0 {
+			u.Unused = append(u.Unused, k)
+		}
+	}
+	sort.Strings(u.Unused)
+	sort.Strings(u.Undeclared)
+	return u
+}
+
+func (u Usage) String() string {
+	var sb strings.Builder
+	sb.WriteString("# declared but never read\n")
+	for _, k := range u.Unused {
+		fmt.Fprintf(&sb, "%s\n", k)
+	}
+	sb.WriteString("# read but never declared\n")
+	for _, k := range u.Undeclared {
+		fmt.Fprintf(&sb, "%s (%d reads)\n", k, u.Reads[k])
+	}
+	return sb.String()
+}
+
+// DumpUsage : write the usage report to w, useful as `defer env.DumpUsage(os.Stderr)`
+func (e *Environment) DumpUsage(w io.Writer) {
+	fmt.Fprint(w, e.UsageReport())
+}
+
+// DumpUsageOnSignal : write the usage report to w every time one of sigs is
+// received. Returns a function that stops listening.
+func (e *Environment) DumpUsageOnSignal(w io.Writer, sigs ...os.Signal) (stop func()) {
+	c := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(c, sigs...)
+	go func() {
+		for {
+			select {
+			case <-c:
+				e.DumpUsage(w)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(c)
+		close(done)
+	}
+}
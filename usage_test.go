@@ -0,0 +1,37 @@
+package env_test
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/taybart/env"
+	"github.com/taybart/env/envtest"
+)
+
+func TestUsageReport(t *testing.T) {
+	is := is.New(t)
+	e := envtest.Global(t, nil)
+	e.Setenv("TEST_USAGE_READ", "read")
+	e.Setenv("TEST_USAGE_UNREAD", "unread")
+	e.Setenv("TEST_USAGE_UNDECLARED", "undeclared")
+	env.Add([]string{"TEST_USAGE_READ", "TEST_USAGE_UNREAD"})
+
+	env.Get("TEST_USAGE_READ")
+	env.Get("TEST_USAGE_READ")
+	env.Get("TEST_USAGE_UNDECLARED")
+
+	u := env.UsageReport()
+	is.Equal(u.Reads["TEST_USAGE_READ"], 2)
+	is.True(contains(u.Unused, "TEST_USAGE_UNREAD"))
+	is.True(!contains(u.Unused, "TEST_USAGE_READ"))
+	is.True(contains(u.Undeclared, "TEST_USAGE_UNDECLARED"))
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
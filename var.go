@@ -0,0 +1,243 @@
+package env
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/taybart/log"
+)
+
+/* Var : a typed handle to a declared variable, so the key is only written once
+ *   var port = env.IntVar("PORT").Default(8080).Range(1, 65535).Describe("HTTP port")
+ *   http.ListenAndServe(fmt.Sprintf(":%d", port.Get()), nil)
+ * Every builder method updates the declaration in the registry, the same one
+ * Add uses. Nothing is validated while building, check the whole registry
+ * with Validate once everything is declared. Defaults of handles are kept in
+ * memory and never written to the process env.
+ */
+type Var[T any] struct {
+	scope  Scope
+	spec   Spec
+	parse  func(string) (T, error)
+	format func(T) string
+	cache  atomic.Pointer[parsedValue[T]]
+}
+
+// parsedValue : the last value Get parsed, reused until the raw value changes
+type parsedValue[T any] struct {
+	raw string
+	val T
+}
+
+// newVar : declare key in scope, validator checks the type of the value
+func newVar[T any](s Scope, key string, validator string, parse func(string) (T, error), format func(T) string) *Var[T] {
+	for i := 0; i < len(key); i++ {
+		if !isNameByte(key[i], i == 0) {
+			panic(fmt.Sprintf("invalid env name %q", key))
+		}
+	}
+	v := &Var[T]{scope: s, parse: parse, format: format}
+	v.spec.Name = s.Key(key)
+	// a key declared with Add keeps its declaration, the handle adds to it
+	if spec, ok := s.environment().Spec(v.spec.Name); ok {
+		v.spec = spec
+	}
+	if validator != "" && !v.hasValidator(validator) {
+		v.spec.Validators = append(v.spec.Validators, Validator{Name: validator})
+	}
+	return v.register()
+}
+
+// hasValidator : returns if the spec already runs the validator name
+func (v *Var[T]) hasValidator(name string) bool {
+	for _, val := range v.spec.Validators {
+		if val.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// register : replace the declaration of the handle in the registry, a
+// default differing from one declared before panics like Add does
+func (v *Var[T]) register() *Var[T] {
+	e := v.scope.environment()
+	if e.IsFrozen() {
+		panic(ErrFrozen)
+	}
+	spec := v.spec
+	e.declare(spec.Name)
+	var prev string
+	var differs bool
+	e.update(func(r *registry) {
+		if spec.HasDefault {
+			prev, differs = r.defaults[spec.Name]
+			if differs = differs && prev != spec.Default; differs {
+				return
+			}
+			r.defaults[spec.Name] = spec.Default
+		}
+		r.specs[spec.Name] = spec
+	})
+	if differs {
+		panic(fmt.Sprintf("Differing default value for %s [ %s!=%s ]\n", spec.Name, shown(spec, prev), shown(spec, spec.Default)))
+	}
+	v.cache.Store(nil)
+	return v
+}
+
+// Name : the fully qualified key of the handle
+func (v *Var[T]) Name() string {
+	return v.spec.Name
+}
+
+// Spec : the declaration of the handle
+func (v *Var[T]) Spec() Spec {
+	return v.spec
+}
+
+// Default : value used when the key is unset
+func (v *Var[T]) Default(val T) *Var[T] {
+	v.spec.Default, v.spec.HasDefault = v.format(val), true
+	return v.register()
+}
+
+// Optional : the zero value is used when the key is unset, like NAME?
+func (v *Var[T]) Optional() *Var[T] {
+	v.spec.Optional = true
+	return v.register()
+}
+
+// AllowEmpty : the key is required but may be blank, like NAME*
+func (v *Var[T]) AllowEmpty() *Var[T] {
+	v.spec.AllowEmpty = true
+	return v.register()
+}
+
+// Secret : redact the value wherever env prints it, like NAME!
+func (v *Var[T]) Secret() *Var[T] {
+	v.spec.Secret = true
+	return v.register()
+}
+
+// Describe : document the key for ConfigReport and scanenv
+func (v *Var[T]) Describe(description string) *Var[T] {
+	v.spec.Description = description
+	return v.register()
+}
+
+// Range : the value must be between lo and hi, for int, float and duration
+// handles. Other handles panic like Check does with invalid arguments.
+func (v *Var[T]) Range(lo, hi T) *Var[T] {
+	return v.Check("range", v.format(lo), v.format(hi))
+}
+
+// OneOf : the value must be one of vals
+func (v *Var[T]) OneOf(vals ...T) *Var[T] {
+	args := make([]string, len(vals))
+	for i, val := range vals {
+		args[i] = v.format(val)
+	}
+	return v.Check("oneof", args...)
+}
+
+// Check : add a validator by name, built in or from RegisterValidator
+func (v *Var[T]) Check(name string, args ...string) *Var[T] {
+	validator := Validator{Name: name, Args: args}
+	if err := checkValidator(validator); err != nil {
+		panic(err)
+	}
+	v.spec.Validators = append(v.spec.Validators, validator)
+	return v.register()
+}
+
+// Value : the parsed value, the zero value when an optional key is unset
+func (v *Var[T]) Value() (T, error) {
+	var zero T
+	raw, found := v.scope.environment().lookup(v.spec.Name)
+	if !found && v.spec.Optional {
+		return zero, nil
+	}
+	if c := v.cache.Load(); c != nil && found && c.raw == raw {
+		return c.val, nil
+	}
+	if err := checkValue(v.spec, raw, found); err != nil {
+		return zero, err
+	}
+	val, err := v.parse(raw)
+	if err != nil {
+		if v.spec.Secret {
+			return zero, fmt.Errorf("%s is not a valid %T", v.spec.Name, zero)
+		}
+		return zero, fmt.Errorf("%s: %w", v.spec.Name, err)
+	}
+	v.cache.Store(&parsedValue[T]{raw: raw, val: val})
+	return val, nil
+}
+
+// Get : the parsed value, exits if it is missing or invalid like Int does
+func (v *Var[T]) Get() T {
+	val, err := v.Value()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return val
+}
+
+// StringVar : declare a string handle
+func (e *Environment) StringVar(key string) *Var[string] { return e.WithPrefix("").StringVar(key) }
+
+// IntVar : declare an int handle
+func (e *Environment) IntVar(key string) *Var[int] { return e.WithPrefix("").IntVar(key) }
+
+// FloatVar : declare a float64 handle
+func (e *Environment) FloatVar(key string) *Var[float64] { return e.WithPrefix("").FloatVar(key) }
+
+// BoolVar : declare a bool handle
+func (e *Environment) BoolVar(key string) *Var[bool] { return e.WithPrefix("").BoolVar(key) }
+
+// DurationVar : declare a time.Duration handle
+func (e *Environment) DurationVar(key string) *Var[time.Duration] {
+	return e.WithPrefix("").DurationVar(key)
+}
+
+// StringVar : same as env.StringVar with key prefixed
+func (s Scope) StringVar(key string) *Var[string] {
+	return newVar(s, key, "", func(val string) (string, error) { return val, nil },
+		func(val string) string { return val })
+}
+
+// IntVar : same as env.IntVar with key prefixed
+func (s Scope) IntVar(key string) *Var[int] {
+	return newVar(s, key, "int", strconv.Atoi, strconv.Itoa)
+}
+
+// FloatVar : same as env.FloatVar with key prefixed
+func (s Scope) FloatVar(key string) *Var[float64] {
+	return newVar(s, key, "float",
+		func(val string) (float64, error) { return strconv.ParseFloat(val, 64) },
+		func(val float64) string { return strconv.FormatFloat(val, 'g', -1, 64) })
+}
+
+// BoolVar : same as env.BoolVar with key prefixed
+func (s Scope) BoolVar(key string) *Var[bool] {
+	return newVar(s, key, "bool", strconv.ParseBool, strconv.FormatBool)
+}
+
+// DurationVar : same as env.DurationVar with key prefixed
+func (s Scope) DurationVar(key string) *Var[time.Duration] {
+	return newVar(s, key, "duration", time.ParseDuration, time.Duration.String)
+}
+
+// Validate : check every declared key, including handles, against its
+// current value
+func (e *Environment) Validate() error {
+	return e.validateSpecs(func(key string) (string, bool) {
+		if val, found, ok := e.frozenLookup(key); ok {
+			return val, found
+		}
+		return e.resolve(key)
+	})
+}
@@ -0,0 +1,110 @@
+package env_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/taybart/env"
+	"github.com/taybart/env/envtest"
+)
+
+func TestVar(t *testing.T) {
+	is := is.New(t)
+	e := envtest.New(t, map[string]string{
+		"TIMEOUT":   "5s",
+		"RATIO":     "0.5",
+		"DB_SECURE": "true",
+	})
+
+	port := e.IntVar("PORT").Default(8080).Range(1, 65535).Describe("HTTP port")
+	timeout := e.DurationVar("TIMEOUT")
+	ratio := e.FloatVar("RATIO").Range(0, 1)
+	secure := e.WithPrefix("DB_").BoolVar("SECURE")
+	mode := e.StringVar("MODE").Optional().OneOf("fast", "safe")
+	is.NoErr(e.Validate())
+
+	is.Equal(port.Get(), 8080)
+	is.Equal(timeout.Get(), 5*time.Second)
+	is.Equal(ratio.Get(), 0.5)
+	is.True(secure.Get())
+	is.Equal(secure.Name(), "DB_SECURE")
+	is.Equal(mode.Get(), "")
+
+	// handles share the registry with Add
+	spec, ok := e.Spec("PORT")
+	is.True(ok)
+	is.Equal(spec.String(), "PORT=8080 | int | range(1,65535) # HTTP port")
+	is.True(e.IsDefault("PORT"))
+	is.Equal(e.Int("PORT"), 8080)
+
+	e.Setenv("PORT", "9090")
+	is.Equal(port.Get(), 9090)
+	e.Setenv("PORT", "70000")
+	_, err := port.Value()
+	is.True(err != nil)
+	is.True(e.Validate() != nil)
+
+	e.Setenv("MODE", "slow")
+	_, err = mode.Value()
+	is.True(err != nil)
+
+	token := e.IntVar("TOKEN").Secret()
+	_, err = token.Value() // unset and required
+	is.True(err != nil)
+	e.Setenv("TOKEN", "hunter2")
+	_, err = token.Value()
+	is.True(err != nil)
+	is.Equal(err.Error(), "TOKEN failed validation") // the value is not leaked
+
+	e.Freeze()
+	defer func() {
+		is.True(errors.Is(recover().(error), env.ErrFrozen))
+	}()
+	e.IntVar("LATE")
+}
+
+func TestVarDurationRange(t *testing.T) {
+	is := is.New(t)
+	e := envtest.New(t, map[string]string{"GRACE": "30s"})
+
+	grace := e.DurationVar("GRACE").Range(time.Second, time.Minute)
+	is.Equal(grace.Get(), 30*time.Second)
+	e.Setenv("GRACE", "2m")
+	_, err := grace.Value()
+	is.True(err != nil)
+	is.Equal(err.Error(), `GRACE failed range(1s,1m0s): 2m is not between 1s and 1m0s`)
+
+	// a range of strings or bools can't be checked
+	defer func() {
+		is.True(recover() != nil)
+	}()
+	e.StringVar("NAME").Range("a", "z")
+}
+
+func TestVarAfterAdd(t *testing.T) {
+	is := is.New(t)
+	e := envtest.New(t, nil)
+	e.DefaultsInProcess(false)
+	e.Add([]string{"CHK_PORT=8080 | range(1,9000) # port"})
+	e.AddComputed("CHK_HOST", func() (string, error) { return "computed", nil })
+
+	// the handle keeps what Add declared
+	port := e.IntVar("CHK_PORT")
+	is.True(e.Has("CHK_PORT"))
+	is.Equal(port.Get(), 8080)
+	is.Equal(port.Spec().String(), "CHK_PORT=8080 | range(1,9000) | int # port")
+	host := e.StringVar("CHK_HOST")
+	is.Equal(host.Get(), "computed")
+	is.Equal(e.ProvenanceOf("CHK_HOST").Kind, env.FromComputed)
+	is.NoErr(e.Validate())
+
+	// the same default is fine, a different one is not
+	port.Default(8080)
+	defer func() {
+		is.True(recover() != nil)
+		is.Equal(port.Get(), 8080)
+	}()
+	e.IntVar("CHK_PORT").Default(9090)
+}